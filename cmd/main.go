@@ -3,26 +3,110 @@ package main
 import (
 	"CodeXecutor/internal/app"
 	"CodeXecutor/internal/worker"
+	"CodeXecutor/models"
+	"CodeXecutor/pkg/config"
+	"CodeXecutor/pkg/database"
+	"CodeXecutor/pkg/logging"
+	"CodeXecutor/pkg/redis"
+	"CodeXecutor/pkg/tracing"
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
 )
 
 func main() {
-	ctx := context.Background()
+	// Stop serving, taking jobs and waiting on Redis and Docker on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Load and validate the configuration before anything uses it
+	cfg, err := config.Init(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	models.SetLanguages(cfg.LanguageTable())
+
+	// Log as configured before anything else logs
+	if err := logging.Setup(); err != nil {
+		log.Fatalf("Error setting up logging: %v", err)
+	}
+
+	// Export spans before the first request is traced
+	shutdownTracing, err := tracing.Setup(ctx)
+	if err != nil {
+		logging.Fatal("Error setting up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Connect to the database and migrate it before serving requests
+	db, err := database.Open(database.GetConfig().Database)
+	if err != nil {
+		logging.Fatal("Error opening database", "error", err)
+	}
+	defer db.Close()
+	go database.RunRetention(ctx, db)
+
+	// Connect to Redis, shared by the server and the worker pool
+	redisStore, err := redis.NewStore(ctx, cfg.Redis)
+	if err != nil {
+		logging.Fatal("Error connecting to Redis", "error", err)
+	}
+	defer redisStore.Close()
 
 	// Initialize the application server
-	server := app.NewServer()
+	server := app.NewServer(cfg.Server.Addr, redisStore, db)
 	server.Start()
 
-	// Initialize the worker pool with min and max worker limits
-	workerPool := worker.NewWorkerPool(ctx, 1, 4)
-	defer workerPool.Stop()
+	// Initialize the worker pool with min and max worker limits. It is stopped after the server,
+	// so the jobs of requests in progress can finish
+	workerPool := worker.NewWorkerPool(context.Background(), redisStore, db, cfg.Workers.Min, cfg.Workers.Max, workerLimits(cfg))
+
+	// Apply changes of the languages, limits and pool bounds without a restart
+	go config.Watch(ctx, func() { reloadConfig(workerPool) })
 
-	// Wait for termination signal
+	// Wait for termination signal, a second one ends the process at once
 	<-ctx.Done()
+	stop()
 
 	// Graceful shutdown
+	slog.Info("Shutting down")
 	server.Stop()
+	workerPool.Stop()
+
+	slog.Info("Server gracefully stopped")
+}
+
+// workerLimits returns the execution limits of the worker pool from the configuration.
+func workerLimits(cfg *config.Config) worker.Limits {
+	return worker.Limits{MaxConcurrent: cfg.MaxConcurrent(), MemoryBudget: cfg.Limits.MemoryBudget()}
+}
+
+// reloadConfig swaps in the reloaded languages, limits and pool bounds.
+// Jobs already admitted keep the settings of their language and the capacity they hold.
+func reloadConfig(workerPool *worker.WorkerPool) {
+	previous := config.Get()
+	cfg, err := config.Reload()
+	if err != nil {
+		slog.Error("Invalid configuration, keeping the current one", "error", err)
+		return
+	}
+
+	languages := cfg.LanguageTable()
+	models.SetLanguages(languages)
+	workerPool.Reconfigure(cfg.Workers.Min, cfg.Workers.Max, workerLimits(cfg))
+	slog.Info("Configuration reloaded", "languages", len(languages), "min_workers", cfg.Workers.Min, "max_workers", cfg.Workers.Max)
 
-	log.Println("Server gracefully stopped")
+	if cfg.Server != previous.Server || !reflect.DeepEqual(cfg.Redis, previous.Redis) {
+		slog.Warn("Changes to the server and Redis settings take effect after a restart")
+	}
 }
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"CodeXecutor/internal/middleware"
+	"CodeXecutor/internal/response"
+	"CodeXecutor/models"
+	"CodeXecutor/pkg/logging"
+	"CodeXecutor/pkg/metrics"
+	"CodeXecutor/pkg/tracing"
+	"CodeXecutor/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+)
+
+// maxBatchSize caps the number of jobs in a single batch.
+const maxBatchSize = 1000
+
+// batchRequest is the body of a batch submission.
+type batchRequest struct {
+	Jobs []submission `json:"jobs"`
+}
+
+// batchJob is the state of a single job of a batch.
+type batchJob struct {
+	ID     string                 `json:"id"`
+	Status string                 `json:"status"`
+	Result map[string]interface{} `json:"result,omitempty"`
+}
+
+// HandleBatchSubmission handles the submission of many jobs at once.
+// All jobs are enqueued in a single Redis round trip and can be followed through the returned batch ID.
+func (h *Handler) HandleBatchSubmission(w http.ResponseWriter, r *http.Request) {
+	var request batchRequest
+	if err := decodeJSON(w, r, &request, maxBatchRequestSize); err != nil {
+		response.Fail(w, r, err)
+		return
+	}
+
+	if len(request.Jobs) == 0 || len(request.Jobs) > maxBatchSize {
+		response.Fail(w, r, response.InvalidFields(response.FieldError{Field: "jobs", Message: fmt.Sprintf("must contain between 1 and %d jobs", maxBatchSize)}))
+		return
+	}
+
+	submitted := make([]models.Job, len(request.Jobs))
+	var fields []response.FieldError
+	for i := range request.Jobs {
+		submitted[i] = request.Jobs[i].job()
+		fields = append(fields, validateJob(submitted[i], fmt.Sprintf("jobs[%d].", i))...)
+	}
+	if len(fields) > 0 {
+		countSubmissions(metrics.SubmissionRejected, submitted...)
+		response.Fail(w, r, response.InvalidFields(fields...))
+		return
+	}
+
+	batch := models.Batch{
+		ID:     utils.GenerateUniqueID(),
+		JobIDs: make([]string, 0, len(request.Jobs)),
+		Time:   int(time.Now().Unix()),
+		Tenant: middleware.TenantFromContext(r.Context()),
+	}
+
+	jobs := make([]models.Job, 0, len(submitted))
+	for _, job := range submitted {
+		job = prepareJob(r, job)
+		job.BatchID = batch.ID
+
+		jobs = append(jobs, job)
+		batch.JobIDs = append(batch.JobIDs, job.ID)
+	}
+
+	h.persistJobs(r, jobs...)
+	span := startEnqueue(r, len(jobs))
+	err := h.store.EnqueueBatch(r.Context(), queueName, batch, jobs)
+	tracing.End(span, err)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to enqueue batch", "error", err)
+		countSubmissions(metrics.SubmissionFailed, jobs...)
+		response.Fail(w, r, unavailable("Failed to submit batch"))
+		return
+	}
+	countSubmissions(metrics.SubmissionQueued, jobs...)
+	logging.FromContext(r.Context()).Info("Batch queued", "batch_id", batch.ID, "count", len(jobs))
+
+	response.JSON(w, r, http.StatusAccepted, map[string]interface{}{"batchid": batch.ID, "jobids": batch.JobIDs})
+}
+
+// HandleBatch reports the progress of a batch and the status of each of its jobs.
+func (h *Handler) HandleBatch(w http.ResponseWriter, r *http.Request) {
+	batch, jobs, ok := h.loadBatch(w, r)
+	if !ok {
+		return
+	}
+
+	counts := map[string]int{}
+	for i := range jobs {
+		counts[jobs[i].Status]++
+		// Results are only part of the download
+		jobs[i].Result = nil
+	}
+	finished := counts[models.StatusCompleted] + counts[models.StatusCancelled]
+
+	response.JSON(w, r, http.StatusOK, map[string]interface{}{
+		"id":       batch.ID,
+		"time":     batch.Time,
+		"total":    len(jobs),
+		"finished": finished,
+		"progress": float64(finished) / float64(len(jobs)),
+		"statuses": counts,
+		"jobs":     jobs,
+	})
+}
+
+// HandleBatchResults downloads the results of all finished jobs of a batch as one JSON document.
+func (h *Handler) HandleBatchResults(w http.ResponseWriter, r *http.Request) {
+	batch, jobs, ok := h.loadBatch(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"batch-%s.json\"", batch.ID))
+	response.JSON(w, r, http.StatusOK, map[string]interface{}{"id": batch.ID, "time": batch.Time, "jobs": jobs})
+}
+
+// loadBatch looks up the requested batch with the state of its jobs, responding with an error if it fails.
+func (h *Handler) loadBatch(w http.ResponseWriter, r *http.Request) (models.Batch, []batchJob, bool) {
+	batchID := mux.Vars(r)["id"]
+	if !utils.IsUniqueID(batchID) {
+		response.Fail(w, r, notFound("Batch not found"))
+		return models.Batch{}, nil, false
+	}
+
+	batch, err := h.store.GetBatch(r.Context(), jobKey(r, batchID))
+	if err == redis.Nil {
+		response.Fail(w, r, notFound("Batch not found"))
+		return batch, nil, false
+	} else if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to get batch", "batch_id", batchID, "error", err)
+		response.Fail(w, r, unavailable("Failed to get batch"))
+		return batch, nil, false
+	}
+
+	results, err := h.store.GetBatchResults(r.Context(), batch.Key())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to get batch results", "batch_id", batchID, "error", err)
+		response.Fail(w, r, unavailable("Failed to get batch"))
+		return batch, nil, false
+	}
+
+	keys := make([]string, len(batch.JobIDs))
+	for i, jobID := range batch.JobIDs {
+		keys[i] = models.JobKey(batch.Tenant, jobID)
+	}
+
+	statuses, err := h.store.GetJobStatuses(r.Context(), keys)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to get batch statuses", "batch_id", batchID, "error", err)
+		response.Fail(w, r, unavailable("Failed to get batch"))
+		return batch, nil, false
+	}
+
+	jobs := make([]batchJob, len(batch.JobIDs))
+	for i, jobID := range batch.JobIDs {
+		jobs[i] = batchJob{ID: jobID, Status: statuses[i]}
+
+		// Results outlive the job statuses, so a stored result is authoritative
+		if result, ok := results[jobID]; ok {
+			jobs[i].Status = result.Status
+			jobs[i].Result = resultData(result)
+		} else if jobs[i].Status == "" {
+			jobs[i].Status = "unknown"
+		}
+	}
+
+	return batch, jobs, true
+}
@@ -1,136 +1,438 @@
 package handler
 
 import (
+	"CodeXecutor/internal/middleware"
+	"CodeXecutor/internal/response"
 	"CodeXecutor/models"
-	redisClient "CodeXecutor/pkg/redis"
+	"CodeXecutor/pkg/config"
+	"CodeXecutor/pkg/database"
+	"CodeXecutor/pkg/logging"
+	"CodeXecutor/pkg/metrics"
+	"CodeXecutor/pkg/tracing"
+	"CodeXecutor/pkg/webhook"
 	"CodeXecutor/utils"
-	"encoding/json"
-	"io"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/redis/go-redis/v9"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type Response struct {
-	Message      string `json:"message"`
-	SubmissionId string `json:"submissionid"`
-}
+// queueName is the Redis queue holding code submissions for the workers.
+const queueName = "code-submissions"
+
+// maxWait bounds how long a request may block waiting for a result.
+const maxWait = 30 * time.Second
 
-// HandleResponse writes a JSON response to the http.ResponseWriter.
-func HandleResponse(w http.ResponseWriter, status int, err error, result models.CompilationResult) {
+// submissionWait is how long /submit waits for the result when the client does not ask otherwise.
+const submissionWait = 500 * time.Millisecond
 
-	response := map[string]interface{}{
-		"found": err != redis.Nil,
-		"data":  nil,
+// resultData renders the result of a job as returned to clients.
+func resultData(result models.CompilationResult) map[string]interface{} {
+	return map[string]interface{}{
+		"status":   result.Status,
+		"output":   result.Output,
+		"error":    errorMessage(result.Error),
+		"exitcode": result.ExitCode,
+		"cached":   result.Cached,
 	}
+}
 
-	if err != nil && err != redis.Nil {
-		// Handle Redis error
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// lookupData builds the data of a result lookup, holding the result if the job has one yet.
+func lookupData(jobID, status string, result *models.CompilationResult) map[string]interface{} {
+	data := map[string]interface{}{
+		"submissionid": jobID,
+		"found":        result != nil,
+		"status":       status,
+		"result":       nil,
 	}
+	if result != nil {
+		data["status"] = result.Status
+		data["result"] = resultData(*result)
+	}
+	return data
+}
 
-	if response["found"].(bool) {
-		// Key found
-		response["data"] = map[string]interface{}{
-			"output":   result.Output,
-			"error":    result.Error,
-			"exitcode": result.ExitCode,
-		}
+// errorMessage returns the message of err, or nil if there is no error.
+func errorMessage(err error) interface{} {
+	if err == nil {
+		return nil
 	}
+	return err.Error()
+}
 
-	sendJSONResponse(w, response, http.StatusOK)
+// notFound returns the error of a request for something the tenant does not have.
+func notFound(message string) *response.Error {
+	return response.NewError(http.StatusNotFound, response.CodeNotFound, message)
 }
 
-// HandleError handles HTTP errors and writes a JSON response with an error message.
-func HandleError(w http.ResponseWriter, status int, err error) {
-	log.Printf("Error: %v", err)
-	HandleResponse(w, status, err, models.CompilationResult{})
+// unavailable returns the error of a request failing on Redis, which the client may retry.
+func unavailable(message string) *response.Error {
+	return response.NewError(http.StatusServiceUnavailable, response.CodeUnavailable, message)
 }
 
 // HandleSubmissionResponse handles the response after submitting code.
-func HandleSubmissionResponse(w http.ResponseWriter, key string, status int) {
-	message := models.CompilationResult{}
+// It waits up to wait for the result, returning as soon as the worker stores it.
+// Without a result yet the response is 202 Accepted.
+func (h *Handler) HandleSubmissionResponse(w http.ResponseWriter, r *http.Request, job models.Job, wait time.Duration) {
+	result, err := h.store.WaitForResult(r.Context(), job.Key(), wait)
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			// The job is queued, only the early result could not be retrieved
+			logging.FromContext(r.Context()).Error("Failed to wait for result", "job_id", job.ID, "error", err)
+		}
+		response.JSON(w, r, http.StatusAccepted, lookupData(job.ID, models.StatusQueued, nil))
+		return
+	}
 
-	// Wait for 0.5 second (500 milliseconds)
-	time.Sleep(500 * time.Millisecond)
+	response.JSON(w, r, http.StatusOK, lookupData(job.ID, result.Status, &result))
+}
 
-	// Retrieve result if available
-	result, err := redisClient.GetCache(key)
-	if err == nil {
-		// Set the response code and message based on the result
-		status = http.StatusOK
-		message = result
+// parseWait reads the wait query parameter, given either as a duration ("2.5s") or in seconds ("10").
+func parseWait(r *http.Request, defaultWait time.Duration) (time.Duration, error) {
+	value := r.URL.Query().Get("wait")
+	if value == "" {
+		return defaultWait, nil
 	}
 
-	// final response
-	HandleResponse(w, status, nil, message)
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.ParseFloat(value, 64)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid wait %q: expected a duration or a number of seconds", value)
+		}
+		wait = time.Duration(seconds * float64(time.Second))
+	}
+
+	if wait < 0 {
+		return 0, fmt.Errorf("invalid wait %q: must not be negative", value)
+	}
+
+	return min(wait, maxWait), nil
+}
+
+// parseCache reads the cache query parameter, which set to false runs the job even if an identical
+// submission's result is cached.
+func parseCache(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("cache")
+	if value == "" {
+		return true, nil
+	}
+
+	useCache, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid cache %q: expected true or false", value)
+	}
+	return useCache, nil
 }
 
 // HandleCodeSubmission handles incoming code submissions.
-func HandleCodeSubmission(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleCodeSubmission(w http.ResponseWriter, r *http.Request) {
+
+	wait, err := parseWait(r, submissionWait)
+	if err != nil {
+		response.Fail(w, r, response.InvalidFields(response.FieldError{Field: "wait", Message: err.Error()}))
+		return
+	}
+
+	useCache, err := parseCache(r)
+	if err != nil {
+		response.Fail(w, r, response.InvalidFields(response.FieldError{Field: "cache", Message: err.Error()}))
+		return
+	}
 
 	// Extract code submission data from the request
-	job, err := extractCodeSubmission(r)
+	job, err := extractCodeSubmission(w, r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		countSubmissions(metrics.SubmissionRejected, job)
+		response.Fail(w, r, err)
 		return
 	}
 
-	// Specify the Redis queue name for code submissions
-	queueName := "code-submissions"
+	// Answer with the result of an identical submission if there is one, and have the worker store
+	// the result of this one otherwise
+	if config.Get().Cache.Enabled {
+		job.CacheResult = true
+		if useCache && h.respondCached(w, r, job) {
+			return
+		}
+	}
+
+	// Record the status first so a fast worker cannot have it overwritten
+	if err := h.store.SetJobStatus(r.Context(), job.Key(), models.StatusQueued); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to set status", "job_id", job.ID, "error", err)
+	}
+	h.persistJobs(r, job)
 
 	// Enqueue the code submission in Redis for processing
-	err = redisClient.EnqueueItem(queueName, job)
+	span := startEnqueue(r, 1)
+	err = h.store.EnqueueItem(r.Context(), queueName, job)
+	tracing.End(span, err)
 	if err != nil {
-		log.Printf("Failed to enqueue code submission: %v", err)
-		// Handle the error and respond to the user with an error message
-		http.Error(w, "Failed to submit code", http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("Failed to enqueue code submission", "error", err)
+		countSubmissions(metrics.SubmissionFailed, job)
+		response.Fail(w, r, unavailable("Failed to submit code"))
 		return
 	}
+	countSubmissions(metrics.SubmissionQueued, job)
+	logging.FromContext(r.Context()).Info("Job queued", "job_id", job.ID, "language", job.Language)
 
-	HandleSubmissionResponse(w, job.ID, http.StatusAccepted)
+	h.HandleSubmissionResponse(w, r, job, wait)
 }
 
-func extractCodeSubmission(r *http.Request) (models.Job, error) {
-	// Read the request body
-	body, err := io.ReadAll(r.Body)
+// respondCached completes a job with the cached result of an identical submission and responds with it.
+// It reports whether there was a cached result; if not, or if it could not be read, the job has to run.
+func (h *Handler) respondCached(w http.ResponseWriter, r *http.Request, job models.Job) bool {
+	language, ok := job.LanguageSettings()
+	if !ok {
+		return false
+	}
+
+	result, found, err := h.store.GetCachedResult(r.Context(), job.Tenant, job.Fingerprint(language))
 	if err != nil {
-		log.Printf("Failed to read request body: %v", err)
-		// Handle the error and return an appropriate response
-		return models.Job{}, err
+		logging.FromContext(r.Context()).Error("Failed to get cached result", "job_id", job.ID, "error", err)
+		return false
+	} else if !found {
+		return false
 	}
 
-	var job models.Job
-	if err := json.Unmarshal(body, &job); err != nil {
-		log.Printf("Failed to unmarshal JSON: %v", err)
-		// Handle the error and return an appropriate response
+	result.Cached = true
+	h.persistJobs(r, job)
+	h.recordResult(r, job, result)
+	countSubmissions(metrics.SubmissionCached, job)
+	logging.FromContext(r.Context()).Info("Job answered from cache", "job_id", job.ID, "language", job.Language)
+
+	response.JSON(w, r, http.StatusOK, lookupData(job.ID, result.Status, &result))
+	return true
+}
+
+// recordResult stores the result of a job finished without a worker, as a worker would:
+// for clients waiting for it, in the database, in its batch and for its webhook.
+func (h *Handler) recordResult(r *http.Request, job models.Job, result models.CompilationResult) {
+	if err := h.store.SaveResult(r.Context(), job.Key(), result, database.GetConfig().Retention.CacheTTL()); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to set result", "job_id", job.ID, "error", err)
+	}
+	if err := h.db.SaveResult(r.Context(), job, result); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to persist result", "job_id", job.ID, "error", err)
+	}
+	if job.BatchID != "" {
+		if err := h.store.SaveBatchResult(r.Context(), models.JobKey(job.Tenant, job.BatchID), job.ID, result); err != nil {
+			logging.FromContext(r.Context()).Error("Failed to save batch result", "job_id", job.ID, "error", err)
+		}
+	}
+	if job.CallbackURL != "" {
+		if err := webhook.Enqueue(r.Context(), h.store, job, result); err != nil {
+			logging.FromContext(r.Context()).Error("Failed to schedule webhook", "job_id", job.ID, "error", err)
+		}
+	}
+}
+
+func extractCodeSubmission(w http.ResponseWriter, r *http.Request) (models.Job, error) {
+	var submitted submission
+	if err := decodeJSON(w, r, &submitted, maxSubmissionSize); err != nil {
 		return models.Job{}, err
 	}
+	job := submitted.job()
+
+	// The rejected job is returned for the metrics only
+	if fields := validateJob(job, ""); len(fields) > 0 {
+		return job, response.InvalidFields(fields...)
+	}
 
+	return prepareJob(r, job), nil
+}
+
+// prepareJob assigns a validated job a new ID and the time of submission, and ties it to the tenant,
+// the ID and the trace of the request submitting it.
+func prepareJob(r *http.Request, job models.Job) models.Job {
 	job.ID = utils.GenerateUniqueID()
-	return job, nil
+	job.Time = int(time.Now().Unix())
+	job.Tenant = middleware.TenantFromContext(r.Context())
+	job.RequestID = response.RequestID(r.Context())
+	tracing.Inject(r.Context(), &job)
+	return job
+}
+
+// startEnqueue starts the span of enqueueing count jobs of a request.
+func startEnqueue(r *http.Request, count int) trace.Span {
+	_, span := tracing.Start(r.Context(), "enqueue",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("redis"),
+			semconv.MessagingDestinationName(queueName),
+			semconv.MessagingOperationPublish,
+			semconv.MessagingBatchMessageCount(count),
+		),
+	)
+	return span
+}
+
+// persistJobs records submitted jobs in the database.
+// A failure is only logged: the jobs still run, and their results are persisted with them.
+func (h *Handler) persistJobs(r *http.Request, jobs ...models.Job) {
+	if err := h.db.SaveJobs(r.Context(), jobs...); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to persist jobs", "count", len(jobs), "error", err)
+	}
+}
+
+// countSubmissions records submitted jobs in the metrics.
+func countSubmissions(status string, jobs ...models.Job) {
+	for _, job := range jobs {
+		metrics.Submissions.WithLabelValues(metrics.Language(job.Language), status).Inc()
+	}
+}
+
+// jobKey returns the storage key of a job of the tenant making the request.
+// Job IDs given by clients have to be checked with utils.IsUniqueID first.
+func jobKey(r *http.Request, jobID string) string {
+	return models.JobKey(middleware.TenantFromContext(r.Context()), jobID)
 }
 
 // HandleResult handles requests to retrieve code processing results.
-func HandleResult(w http.ResponseWriter, r *http.Request) {
-	key := r.URL.Query().Get("key")
-	result, err := redisClient.GetCache(key)
+// With a wait parameter the request blocks until the result exists or the wait expires.
+// Results that left the cache are read from the database. Unknown jobs are reported as 404 Not Found,
+// finished jobs whose result is gone from both as 410 Gone.
+func (h *Handler) HandleResult(w http.ResponseWriter, r *http.Request) {
+	wait, err := parseWait(r, 0)
 	if err != nil {
-		HandleError(w, http.StatusNoContent, err)
+		response.Fail(w, r, response.InvalidFields(response.FieldError{Field: "wait", Message: err.Error()}))
+		return
+	}
+
+	jobID := r.URL.Query().Get("key")
+	if jobID == "" {
+		response.Fail(w, r, response.InvalidFields(response.FieldError{Field: "key", Message: "is required"}))
+		return
+	} else if !utils.IsUniqueID(jobID) {
+		response.Fail(w, r, response.InvalidFields(response.FieldError{Field: "key", Message: "must be a submission ID"}))
+		return
+	}
+
+	key := jobKey(r, jobID)
+	result, err := h.store.WaitForResult(r.Context(), key, wait)
+	if err == nil {
+		response.JSON(w, r, http.StatusOK, lookupData(jobID, result.Status, &result))
+		return
+	} else if !errors.Is(err, redis.Nil) {
+		logging.FromContext(r.Context()).Error("Failed to get result", "job_id", jobID, "error", err)
+		response.Fail(w, r, unavailable("Failed to get result"))
+		return
+	}
+
+	// The cache only holds recent results, older ones are kept in the database
+	record, err := h.db.GetJob(r.Context(), middleware.TenantFromContext(r.Context()), jobID)
+	if err == nil && record.Result != nil {
+		response.JSON(w, r, http.StatusOK, lookupData(jobID, record.Status, record.Result))
+		return
+	} else if err != nil && !errors.Is(err, database.ErrNotFound) {
+		logging.FromContext(r.Context()).Error("Failed to get job from the database", "job_id", jobID, "error", err)
+		response.Fail(w, r, unavailable("Failed to get result"))
+		return
+	}
+	persisted := err == nil
+
+	// Without a result the status tells whether the job is still running
+	status, err := h.store.GetJobStatus(r.Context(), key)
+	if err == redis.Nil {
+		if persisted {
+			response.JSON(w, r, http.StatusOK, lookupData(jobID, record.Status, nil))
+			return
+		}
+		response.Fail(w, r, notFound("Job not found"))
+		return
+	} else if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to get status", "job_id", jobID, "error", err)
+		response.Fail(w, r, unavailable("Failed to get result"))
+		return
+	}
+
+	if status == models.StatusCompleted || status == models.StatusCancelled {
+		response.Fail(w, r, response.NewError(http.StatusGone, response.CodeGone, "Result has expired"))
 		return
 	}
 
-	HandleResponse(w, http.StatusOK, err, result)
+	response.JSON(w, r, http.StatusOK, lookupData(jobID, status, nil))
 }
 
-func sendJSONResponse(w http.ResponseWriter, data interface{}, statusCode int) {
-	w.WriteHeader(statusCode)
+// HandleCancelJob handles requests to cancel a submitted job.
+// A queued job is removed from the queue, while a running job is stopped by the worker that owns it.
+func (h *Handler) HandleCancelJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+	if !utils.IsUniqueID(jobID) {
+		response.Fail(w, r, notFound("Job not found"))
+		return
+	}
+	key := jobKey(r, jobID)
+
+	status, err := h.store.GetJobStatus(r.Context(), key)
+	if err == redis.Nil {
+		response.Fail(w, r, notFound("Job not found"))
+		return
+	} else if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to get status", "job_id", jobID, "error", err)
+		response.Fail(w, r, unavailable("Failed to cancel job"))
+		return
+	}
 
-	// Encode and write the response
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if status == models.StatusCompleted || status == models.StatusCancelled {
+		response.Fail(w, r, response.NewError(http.StatusConflict, response.CodeConflict, "Job has already finished"))
+		return
+	}
+
+	if status == models.StatusQueued {
+		job, removed, err := h.store.RemoveItem(r.Context(), queueName, key)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Failed to remove job from the queue", "job_id", jobID, "error", err)
+			response.Fail(w, r, unavailable("Failed to cancel job"))
+			return
+		}
+
+		if removed {
+			h.recordResult(r, job, models.CompilationResult{Status: models.StatusCancelled, ExitCode: -1})
+			response.JSON(w, r, http.StatusOK, map[string]interface{}{"id": jobID, "status": models.StatusCancelled})
+			return
+		}
+	}
+
+	// The job has left the queue, so the worker running it has to stop it
+	if err := h.store.CancelJob(r.Context(), key); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to cancel job", "job_id", jobID, "error", err)
+		response.Fail(w, r, unavailable("Failed to cancel job"))
+		return
 	}
+
+	response.JSON(w, r, http.StatusAccepted, map[string]interface{}{"id": jobID, "status": "cancelling"})
+}
+
+// HandleDeliveries handles requests for the webhook delivery log of a job.
+func (h *Handler) HandleDeliveries(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+	if !utils.IsUniqueID(jobID) {
+		response.Fail(w, r, notFound("Job not found"))
+		return
+	}
+
+	attempts, err := h.store.GetDeliveryLog(r.Context(), jobKey(r, jobID))
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to get delivery log", "job_id", jobID, "error", err)
+		response.Fail(w, r, unavailable("Failed to get deliveries"))
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, map[string]interface{}{"id": jobID, "deliveries": attempts})
+}
+
+// HandleNotFound answers requests for unknown routes.
+func HandleNotFound(w http.ResponseWriter, r *http.Request) {
+	response.Fail(w, r, notFound("Route not found"))
+}
+
+// HandleMethodNotAllowed answers requests using a method a route does not support.
+func HandleMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	response.Fail(w, r, response.NewError(http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, "Method not allowed"))
 }
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"CodeXecutor/internal/middleware"
+	"CodeXecutor/models"
+	"CodeXecutor/pkg/config"
+	"CodeXecutor/pkg/database"
+	redisClient "CodeXecutor/pkg/redis"
+	"CodeXecutor/utils"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestHandler returns a handler keeping jobs in Redis and their history in a database of its own.
+func newTestHandler(t *testing.T) *Handler {
+	// Without idle connections dialled in the background, which race with adding the hooks in go-redis v9.3.0
+	redisConfig := config.Default().Redis
+	redisConfig.MinIdleConns = 0
+	store, err := redisClient.NewStore(context.Background(), redisConfig)
+	if !assert.NoError(t, err, "Error connecting to Redis") {
+		t.FailNow()
+	}
+	t.Cleanup(func() { store.Close() })
+
+	db, err := database.Open(database.DatabaseConfig{Driver: "sqlite", DSN: filepath.Join(t.TempDir(), "test.db")})
+	if !assert.NoError(t, err, "Error opening database") {
+		t.FailNow()
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return New(store, db)
+}
+
+// tenantRequest returns a request of an authenticated tenant, with the route variables set.
+func tenantRequest(method, target, tenant string, vars map[string]string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	r = r.WithContext(middleware.WithTenant(r.Context(), tenant))
+	return mux.SetURLVars(r, vars)
+}
+
+// cancel requests the cancellation of a job of a tenant.
+func cancel(h *Handler, tenant, jobID string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.HandleCancelJob(w, tenantRequest("DELETE", "/jobs/"+jobID, tenant, map[string]string{"id": jobID}))
+	return w
+}
+
+func TestJobIDsAreUUIDs(t *testing.T) {
+	// Tenant "a" asking for "b:c" must not reach job "c" of tenant "a:b"
+	h := New(nil, nil)
+
+	w := httptest.NewRecorder()
+	h.HandleResult(w, tenantRequest("GET", "/result?key=b:c", "a", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "A key that is not a submission ID should be rejected")
+
+	assert.Equal(t, http.StatusNotFound, cancel(h, "a", "b:c").Code, "An ID that is not a job ID should not be found")
+}
+
+func TestCancelQueuedJob(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+
+	job := models.Job{ID: utils.GenerateUniqueID(), Tenant: "test", Language: "python", Code: "print(1)"}
+	assert.NoError(t, h.store.SetJobStatus(ctx, job.Key(), models.StatusQueued))
+	assert.NoError(t, h.db.SaveJobs(ctx, job))
+	assert.NoError(t, h.store.EnqueueItem(ctx, queueName, job))
+
+	w := cancel(h, "test", job.ID)
+	assert.Equal(t, http.StatusOK, w.Code, "A queued job should be cancelled at once")
+
+	_, removed, err := h.store.RemoveItem(ctx, queueName, job.Key())
+	assert.NoError(t, err)
+	assert.False(t, removed, "The job should have left the queue")
+
+	result, err := h.store.WaitForResult(ctx, job.Key(), 0)
+	assert.NoError(t, err, "The cancellation should be recorded as the result")
+	assert.Equal(t, models.StatusCancelled, result.Status)
+
+	record, err := h.db.GetJob(ctx, "test", job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, record.Status, "The cancellation should be persisted")
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+
+	job := models.Job{ID: utils.GenerateUniqueID(), Tenant: "test"}
+	assert.NoError(t, h.store.SetJobStatus(ctx, job.Key(), models.StatusRunning))
+
+	cancellations := h.store.SubscribeCancellations(ctx)
+	defer cancellations.Close()
+	_, err := cancellations.Receive(ctx)
+	assert.NoError(t, err, "Error subscribing to cancellations")
+
+	w := cancel(h, "test", job.ID)
+	assert.Equal(t, http.StatusAccepted, w.Code, "A running job should be left to its worker to stop")
+
+	cancelled, err := h.store.IsCancelled(ctx, job.Key())
+	assert.NoError(t, err)
+	assert.True(t, cancelled, "The job should be marked as cancelled")
+
+	// Other jobs may be cancelled at the same time by other tests
+	for {
+		receiveCtx, stop := context.WithTimeout(ctx, 5*time.Second)
+		message, err := cancellations.ReceiveMessage(receiveCtx)
+		stop()
+		if !assert.NoError(t, err, "The workers should be notified") || message.Payload == job.Key() {
+			break
+		}
+	}
+}
+
+func TestCancelUnknownJob(t *testing.T) {
+	h := newTestHandler(t)
+
+	job := models.Job{ID: utils.GenerateUniqueID(), Tenant: "other"}
+	assert.NoError(t, h.store.SetJobStatus(context.Background(), job.Key(), models.StatusQueued))
+
+	assert.Equal(t, http.StatusNotFound, cancel(h, "test", utils.GenerateUniqueID()).Code, "Unknown jobs should not be found")
+	assert.Equal(t, http.StatusNotFound, cancel(h, "test", job.ID).Code, "Jobs of other tenants should not be found")
+}
+
+func TestCancelFinishedJob(t *testing.T) {
+	h := newTestHandler(t)
+
+	job := models.Job{ID: utils.GenerateUniqueID(), Tenant: "test"}
+	assert.NoError(t, h.store.SetJobStatus(context.Background(), job.Key(), models.StatusCompleted))
+
+	assert.Equal(t, http.StatusConflict, cancel(h, "test", job.ID).Code, "Finished jobs cannot be cancelled")
+}
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"CodeXecutor/pkg/database"
+	redisClient "CodeXecutor/pkg/redis"
+)
+
+// Handler serves the requests about jobs, which are queued and tracked in Redis and kept in the database.
+type Handler struct {
+	store *redisClient.Store
+	db    database.Store
+}
+
+// New returns a Handler keeping jobs in store and their history in db.
+func New(store *redisClient.Store, db database.Store) *Handler {
+	return &Handler{store: store, db: db}
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"CodeXecutor/internal/response"
+	"CodeXecutor/pkg/health"
+	"net/http"
+)
+
+// HandleHealth reports that the process is alive and serving requests.
+func HandleHealth(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, r, http.StatusOK, map[string]interface{}{"status": health.StatusOK})
+}
+
+// HandleReady reports whether the service can run jobs, with the state of each component it depends on.
+// A service that is not ready is answered with 503 Service Unavailable.
+func HandleReady(w http.ResponseWriter, r *http.Request) {
+	ready, components := health.Ready(r.Context())
+
+	data := map[string]interface{}{"status": "ready", "components": components}
+	if !ready {
+		data["status"] = "not_ready"
+		response.FailWithData(w, r, response.NewError(http.StatusServiceUnavailable, response.CodeUnavailable, "Service not ready"), data)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, data)
+}
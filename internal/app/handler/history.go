@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"CodeXecutor/internal/middleware"
+	"CodeXecutor/internal/response"
+	"CodeXecutor/models"
+	"CodeXecutor/pkg/database"
+	"CodeXecutor/pkg/logging"
+	"CodeXecutor/pkg/security"
+	"CodeXecutor/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// allTenants is the tenant parameter of admins reading the jobs of every tenant.
+const allTenants = "*"
+
+var (
+	statuses = map[string]bool{models.StatusQueued: true, models.StatusRunning: true, models.StatusCompleted: true, models.StatusCancelled: true}
+	verdicts = map[string]bool{models.VerdictSuccess: true, models.VerdictRuntimeError: true, models.VerdictSystemError: true, models.VerdictCancelled: true}
+)
+
+// HandleListJobs searches the job history, newest first, a page at a time.
+// The summaries leave out the code and output, which are part of the job's detail view.
+func (h *Handler) HandleListJobs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseJobFilter(r)
+	if err != nil {
+		response.Fail(w, r, err)
+		return
+	}
+
+	jobs, next, err := h.db.ListJobs(r.Context(), filter)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		response.Fail(w, r, response.InvalidFields(response.FieldError{Field: "cursor", Message: "is not a cursor returned by this endpoint"}))
+		return
+	} else if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to list jobs", "error", err)
+		response.Fail(w, r, unavailable("Failed to list jobs"))
+		return
+	}
+
+	var nextCursor interface{}
+	if next != "" {
+		nextCursor = next
+	}
+	response.JSON(w, r, http.StatusOK, map[string]interface{}{"jobs": jobs, "next_cursor": nextCursor})
+}
+
+// HandleJob returns the stored code of a job with its full result.
+func (h *Handler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+	if !utils.IsUniqueID(jobID) {
+		response.Fail(w, r, notFound("Job not found"))
+		return
+	}
+
+	tenant, err := requestedTenant(r)
+	if err != nil {
+		response.Fail(w, r, err)
+		return
+	}
+	if tenant == allTenants {
+		response.Fail(w, r, response.InvalidFields(response.FieldError{Field: "tenant", Message: "must name a single tenant"}))
+		return
+	}
+
+	record, err := h.db.GetJob(r.Context(), tenant, jobID)
+	if errors.Is(err, database.ErrNotFound) {
+		response.Fail(w, r, notFound("Job not found"))
+		return
+	} else if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to get job", "job_id", jobID, "error", err)
+		response.Fail(w, r, unavailable("Failed to get job"))
+		return
+	}
+
+	data := map[string]interface{}{
+		"job":          record.Job,
+		"status":       record.Status,
+		"verdict":      nil,
+		"submitted_at": record.SubmittedAt,
+		"finished_at":  nil,
+		"result":       nil,
+	}
+	if record.Result != nil {
+		data["verdict"] = record.Result.Verdict()
+		data["finished_at"] = record.FinishedAt
+		data["result"] = resultData(*record.Result)
+	}
+
+	response.JSON(w, r, http.StatusOK, data)
+}
+
+// requestedTenant returns the tenant whose jobs a request reads, the caller's own unless an admin asks for another.
+func requestedTenant(r *http.Request) (string, error) {
+	caller := middleware.TenantFromContext(r.Context())
+
+	tenant := r.URL.Query().Get("tenant")
+	if tenant == "" || tenant == caller {
+		return caller, nil
+	}
+
+	if !security.IsAdmin(caller) {
+		return "", response.NewError(http.StatusForbidden, response.CodeForbidden, "Only admins may read the jobs of other tenants")
+	}
+	return tenant, nil
+}
+
+// parseJobFilter reads the search parameters of the job history.
+func parseJobFilter(r *http.Request) (database.JobFilter, error) {
+	query := r.URL.Query()
+
+	tenant, err := requestedTenant(r)
+	if err != nil {
+		return database.JobFilter{}, err
+	}
+
+	filter := database.JobFilter{
+		Tenant:   tenant,
+		Language: query.Get("language"),
+		Status:   query.Get("status"),
+		Verdict:  query.Get("verdict"),
+		Cursor:   query.Get("cursor"),
+	}
+	if filter.Tenant == allTenants {
+		filter.Tenant = ""
+	}
+
+	var fields []response.FieldError
+	invalid := func(field, format string, args ...interface{}) {
+		fields = append(fields, response.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if _, ok := models.LookupLanguage(filter.Language); filter.Language != "" && !ok {
+		invalid("language", "unsupported language %q", filter.Language)
+	}
+	if filter.Status != "" && !statuses[filter.Status] {
+		invalid("status", "must be one of queued, running, completed or cancelled")
+	}
+	if filter.Verdict != "" && !verdicts[filter.Verdict] {
+		invalid("verdict", "must be one of success, runtime_error, system_error or cancelled")
+	}
+
+	if value := query.Get("exit_code"); value != "" {
+		exitCode, err := strconv.Atoi(value)
+		if err != nil {
+			invalid("exit_code", "must be an integer")
+		}
+		filter.ExitCode = &exitCode
+	}
+
+	for _, bound := range []struct {
+		field string
+		time  *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if value := query.Get(bound.field); value != "" {
+			parsed, err := parseTime(value)
+			if err != nil {
+				invalid(bound.field, "must be an RFC 3339 time or Unix seconds")
+			}
+			*bound.time = parsed
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > database.MaxPageSize {
+			invalid("limit", "must be between 1 and %d", database.MaxPageSize)
+		}
+		filter.Limit = limit
+	}
+
+	if len(fields) > 0 {
+		return database.JobFilter{}, response.InvalidFields(fields...)
+	}
+	return filter, nil
+}
+
+// parseTime reads a time given in RFC 3339 or as Unix seconds.
+func parseTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"CodeXecutor/internal/middleware"
+	"CodeXecutor/internal/response"
+	"CodeXecutor/models"
+	"CodeXecutor/pkg/logging"
+	"CodeXecutor/pkg/security"
+	"CodeXecutor/pkg/tracing"
+	"CodeXecutor/utils"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Types of the messages exchanged over an interactive session, next to the job event types.
+const (
+	sessionStart   = "start"   // client: starts the session with a language and optional code
+	sessionStdin   = "stdin"   // client: Data holds input for the program
+	sessionCreated = "session" // server: Data holds the ID of the session's job
+	sessionError   = "error"   // server: Data holds why the session could not start
+)
+
+var upgrader = websocket.Upgrader{Error: upgradeError}
+
+// upgradeError answers a request that cannot be upgraded to a WebSocket in the response envelope.
+func upgradeError(w http.ResponseWriter, r *http.Request, status int, reason error) {
+	response.Fail(w, r, response.NewError(status, response.CodeForStatus(status), reason.Error()))
+}
+
+// sessionMessage is a message of an interactive session.
+type sessionMessage struct {
+	Type     string      `json:"type"`
+	Language string      `json:"language,omitempty"`
+	Code     string      `json:"code,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+}
+
+// HandleSession runs an interactive session over a WebSocket.
+// The first message starts the session: the code is run with the client's input relayed to its stdin,
+// or the language's interpreter is started if no code is given. Output and status changes are sent as
+// job events until the final result. The worker ends the session after an idle timeout or a maximum length.
+func (h *Handler) HandleSession(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already responded to the client
+		logging.FromContext(r.Context()).Warn("Failed to upgrade session", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	var start sessionMessage
+	if err := conn.ReadJSON(&start); err != nil || start.Type != sessionStart {
+		conn.WriteJSON(sessionMessage{Type: sessionError, Data: "the first message must start the session"})
+		return
+	}
+
+	language, ok := models.LookupLanguage(start.Language)
+	if !ok {
+		conn.WriteJSON(sessionMessage{Type: sessionError, Data: "unsupported language"})
+		return
+	}
+	if len(start.Code) > security.MaxCodeSize {
+		conn.WriteJSON(sessionMessage{Type: sessionError, Data: fmt.Sprintf("code must not be larger than %d bytes", security.MaxCodeSize)})
+		return
+	}
+	if start.Code == "" && len(language.Repl) == 0 {
+		conn.WriteJSON(sessionMessage{Type: sessionError, Data: "language has no interactive interpreter, code is required"})
+		return
+	}
+
+	job := models.Job{
+		ID:          utils.GenerateUniqueID(),
+		Language:    start.Language,
+		Code:        start.Code,
+		Time:        int(time.Now().Unix()),
+		Interactive: true,
+		Tenant:      middleware.TenantFromContext(r.Context()),
+		RequestID:   response.RequestID(r.Context()),
+	}
+	tracing.Inject(r.Context(), &job)
+
+	if err := h.store.SetJobStatus(r.Context(), job.Key(), models.StatusQueued); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to set status", "job_id", job.ID, "error", err)
+	}
+	h.persistJobs(r, job)
+	span := startEnqueue(r, 1)
+	err = h.store.EnqueueItem(r.Context(), queueName, job)
+	tracing.End(span, err)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to enqueue session", "error", err)
+		conn.WriteJSON(sessionMessage{Type: sessionError, Data: "failed to start session"})
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Session queued", "job_id", job.ID, "language", job.Language)
+
+	if err := conn.WriteJSON(sessionMessage{Type: sessionCreated, Data: job.ID}); err != nil {
+		return
+	}
+
+	// Relay the client's input until it goes away
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			var message sessionMessage
+			if err := conn.ReadJSON(&message); err != nil {
+				return
+			}
+
+			data, ok := message.Data.(string)
+			if message.Type != sessionStdin || !ok {
+				continue
+			}
+			if err := h.store.PushStdin(ctx, job.Key(), data); err != nil {
+				logging.FromContext(r.Context()).Error("Failed to relay input", "job_id", job.ID, "error", err)
+			}
+		}
+	}()
+
+	// Relay the job's events until the session ends
+	lastID := "0"
+	for ctx.Err() == nil {
+		events, err := h.store.ReadEvents(ctx, job.Key(), lastID, eventBlock)
+		if err != nil {
+			break
+		}
+
+		for _, event := range events {
+			payload, err := eventPayload(event)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Failed to read event", "job_id", job.ID, "error", err)
+				continue
+			}
+			if err := conn.WriteJSON(sessionMessage{Type: event.Type, Data: payload}); err != nil {
+				cancel()
+				break
+			}
+			lastID = event.ID
+
+			if event.Type == models.EventResult {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+		}
+	}
+
+	// The client left before the session ended, so nobody is using it anymore
+	if err := h.store.CancelJob(r.Context(), job.Key()); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to cancel job", "job_id", job.ID, "error", err)
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"CodeXecutor/internal/middleware"
+	"CodeXecutor/models"
+	"CodeXecutor/pkg/security"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// dialSession opens a session of the tenant "test" and sends its first message.
+func dialSession(t *testing.T, h *Handler, start interface{}) *websocket.Conn {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.HandleSession(w, r.WithContext(middleware.WithTenant(r.Context(), "test")))
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if !assert.NoError(t, err, "Error opening session") {
+		t.FailNow()
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	assert.NoError(t, conn.WriteJSON(start))
+	return conn
+}
+
+// readUntil reads the messages of a session up to the first one of the given type.
+func readUntil(t *testing.T, conn *websocket.Conn, messageType string) sessionMessage {
+	for {
+		var message sessionMessage
+		if !assert.NoError(t, conn.ReadJSON(&message), "Expected a %s message", messageType) {
+			t.FailNow()
+		}
+		if message.Type == messageType {
+			return message
+		}
+	}
+}
+
+func TestSessionStartValidation(t *testing.T) {
+	h := newTestHandler(t)
+
+	tests := []struct {
+		name  string
+		start sessionMessage
+		error string
+	}{
+		{"not a start message", sessionMessage{Type: sessionStdin, Data: "1"}, "the first message must start the session"},
+		{"unsupported language", sessionMessage{Type: sessionStart, Language: "cobol"}, "unsupported language"},
+		{"code too large", sessionMessage{Type: sessionStart, Language: "python", Code: strings.Repeat("a", security.MaxCodeSize+1)}, "code must not be larger than"},
+		{"no code without an interpreter", sessionMessage{Type: sessionStart, Language: "golang"}, "language has no interactive interpreter, code is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dialSession(t, h, tt.start)
+
+			var message sessionMessage
+			assert.NoError(t, conn.ReadJSON(&message))
+			assert.Equal(t, sessionError, message.Type)
+			assert.Contains(t, message.Data, tt.error)
+
+			// The session is closed without a job being queued
+			_, _, err := conn.ReadMessage()
+			assert.Error(t, err, "The connection should be closed")
+		})
+	}
+}
+
+func TestSessionRelay(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+
+	conn := dialSession(t, h, sessionMessage{Type: sessionStart, Language: "python"})
+	created := readUntil(t, conn, sessionCreated)
+	job := models.Job{ID: created.Data.(string), Tenant: "test"}
+	defer h.store.RemoveItem(ctx, queueName, job.Key())
+
+	// The client's input is queued for the worker
+	assert.NoError(t, conn.WriteJSON(sessionMessage{Type: sessionStdin, Data: "print(1)\n"}))
+	data, err := h.store.PopStdin(ctx, job.Key(), 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "print(1)\n", data, "The input should be relayed to the job")
+
+	// The program's output is sent to the client
+	assert.NoError(t, h.store.AppendEvent(ctx, job.Key(), models.Event{Type: models.EventStdout, Data: "1\n"}))
+	assert.Equal(t, "1\n", readUntil(t, conn, models.EventStdout).Data, "The output should be relayed to the client")
+
+	// The result ends the session
+	assert.NoError(t, h.store.SaveResult(ctx, job.Key(), models.CompilationResult{Status: models.StatusCompleted, Output: "1\n"}, time.Minute))
+	readUntil(t, conn, models.EventResult)
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), "The session should be closed after the result, got %v", err)
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"CodeXecutor/internal/response"
+	"CodeXecutor/models"
+	"CodeXecutor/pkg/logging"
+	"CodeXecutor/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+)
+
+// eventBlock is how long a read of the event stream blocks before the connection is kept alive.
+const eventBlock = 5 * time.Second
+
+// HandleJobEvents streams the status changes, output and final result of a job as Server-Sent Events.
+// The events recorded so far are replayed first, so the stream can be opened at any time,
+// and a reconnecting client resumes after the Last-Event-ID it received.
+func (h *Handler) HandleJobEvents(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+	if !utils.IsUniqueID(jobID) {
+		response.Fail(w, r, notFound("Job not found"))
+		return
+	}
+	key := jobKey(r, jobID)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		response.Fail(w, r, response.NewError(http.StatusInternalServerError, response.CodeInternal, "Streaming is not supported"))
+		return
+	}
+
+	if _, err := h.store.GetJobStatus(r.Context(), key); err == redis.Nil {
+		response.Fail(w, r, notFound("Job not found"))
+		return
+	} else if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to get status", "job_id", jobID, "error", err)
+		response.Fail(w, r, unavailable("Failed to stream job"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = "0"
+	}
+
+	for r.Context().Err() == nil {
+		events, err := h.store.ReadEvents(r.Context(), key, lastID, eventBlock)
+		if err != nil {
+			if r.Context().Err() == nil {
+				logging.FromContext(r.Context()).Error("Failed to read events", "job_id", jobID, "error", err)
+			}
+			return
+		}
+
+		if len(events) == 0 {
+			// A comment line keeps proxies from closing the idle connection
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+			continue
+		}
+
+		for _, event := range events {
+			if err := writeEvent(w, event); err != nil {
+				logging.FromContext(r.Context()).Warn("Failed to write event", "job_id", jobID, "error", err)
+				return
+			}
+			lastID = event.ID
+
+			// The result is always the last event of a job
+			if event.Type == models.EventResult {
+				flusher.Flush()
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes an event in the Server-Sent Events format with a JSON encoded payload.
+func writeEvent(w io.Writer, event models.Event) error {
+	payload, err := eventPayload(event)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// eventPayload returns the data of an event as sent to clients.
+// Results are rendered like the data of /result, everything else is sent as text.
+func eventPayload(event models.Event) (interface{}, error) {
+	if event.Type != models.EventResult {
+		return event.Data, nil
+	}
+
+	var result models.CompilationResult
+	if err := json.Unmarshal([]byte(event.Data), &result); err != nil {
+		return nil, err
+	}
+	return resultData(result), nil
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"CodeXecutor/models"
+	"CodeXecutor/utils"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// streamEvents requests the events of a job of the tenant "test" and returns the response once the stream ends.
+func streamEvents(t *testing.T, h *Handler, jobID, lastEventID string) *httptest.ResponseRecorder {
+	r := tenantRequest("GET", "/jobs/"+jobID+"/events", "test", map[string]string{"id": jobID})
+	if lastEventID != "" {
+		r.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.HandleJobEvents(w, r)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("The stream should end after the result")
+	}
+	return w
+}
+
+// runJob records a job of the tenant "test" that started, wrote two lines and finished.
+// Its events are the status, the two lines, the status and the result.
+func runJob(t *testing.T, h *Handler) (models.Job, []models.Event) {
+	ctx := context.Background()
+	job := models.Job{ID: utils.GenerateUniqueID(), Tenant: "test"}
+
+	assert.NoError(t, h.store.SetJobStatus(ctx, job.Key(), models.StatusRunning))
+	assert.NoError(t, h.store.AppendEvent(ctx, job.Key(), models.Event{Type: models.EventStdout, Data: "first\n"}))
+	assert.NoError(t, h.store.AppendEvent(ctx, job.Key(), models.Event{Type: models.EventStdout, Data: "second\n"}))
+	assert.NoError(t, h.store.SaveResult(ctx, job.Key(), models.CompilationResult{Status: models.StatusCompleted, Output: "first\nsecond\n"}, time.Minute))
+
+	events, err := h.store.ReadEvents(ctx, job.Key(), "0", 0)
+	assert.NoError(t, err)
+	return job, events
+}
+
+func TestJobEventsReplay(t *testing.T) {
+	h := newTestHandler(t)
+	job, events := runJob(t, h)
+
+	w := streamEvents(t, h, job.ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.Contains(t, body, "id: "+events[1].ID+"\nevent: stdout\ndata: \"first\\n\"\n\n", "Recorded events should be replayed")
+	assert.Less(t, strings.Index(body, `"first\n"`), strings.Index(body, `"second\n"`), "Events should be replayed in order")
+	assert.True(t, strings.HasSuffix(body, "\"status\":\"completed\"}\n\n"), "The stream should end with the result")
+}
+
+func TestJobEventsResume(t *testing.T) {
+	h := newTestHandler(t)
+	job, events := runJob(t, h)
+
+	body := streamEvents(t, h, job.ID, events[1].ID).Body.String()
+	assert.NotContains(t, body, `"first\n"`, "Events up to Last-Event-ID should not be sent again")
+	assert.True(t, strings.HasPrefix(body, "id: "+events[2].ID+"\n"), "The stream should resume after Last-Event-ID")
+	assert.Contains(t, body, "event: result\n")
+}
+
+func TestJobEventsEndAfterResult(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	job := models.Job{ID: utils.GenerateUniqueID(), Tenant: "test"}
+	assert.NoError(t, h.store.SetJobStatus(ctx, job.Key(), models.StatusRunning))
+
+	// The result arrives while the client is waiting for events
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		h.store.SaveResult(ctx, job.Key(), models.CompilationResult{Status: models.StatusCompleted}, time.Minute)
+		h.store.AppendEvent(ctx, job.Key(), models.Event{Type: models.EventStdout, Data: "late\n"})
+	}()
+
+	body := streamEvents(t, h, job.ID, "").Body.String()
+	assert.Contains(t, body, "event: result\n", "The result should be streamed as it arrives")
+	assert.NotContains(t, body, "late", "Nothing should be sent after the result")
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"CodeXecutor/internal/response"
+	"CodeXecutor/models"
+	"CodeXecutor/pkg/logging"
+	"CodeXecutor/pkg/security"
+	"CodeXecutor/pkg/webhook"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	maxSubmissionSize   = 2 << 20  // Maximum size of a code submission body in bytes
+	maxBatchRequestSize = 32 << 20 // Maximum size of a batch submission body in bytes
+)
+
+// submission is a job as submitted by clients, holding only the fields they may set.
+// Fields set by the server, like the ID and the tenant, are unknown fields of a submission and rejected.
+type submission struct {
+	Language    string            `json:"language"`
+	Code        string            `json:"code"`
+	Files       map[string]string `json:"files,omitempty"`
+	Entrypoint  string            `json:"entrypoint,omitempty"`
+	CallbackURL string            `json:"callback_url,omitempty"`
+	Stdin       string            `json:"stdin,omitempty"`
+	TimeLimit   float64           `json:"time_limit,omitempty"`
+	MemoryLimit int64             `json:"memory_limit,omitempty"`
+}
+
+// job returns the job of a submission, without any of the fields set by the server.
+func (s submission) job() models.Job {
+	return models.Job{
+		Language:    s.Language,
+		Code:        s.Code,
+		Files:       s.Files,
+		Entrypoint:  s.Entrypoint,
+		CallbackURL: s.CallbackURL,
+		Stdin:       s.Stdin,
+		TimeLimit:   s.TimeLimit,
+		MemoryLimit: s.MemoryLimit,
+	}
+}
+
+// decodeJSON decodes a request body holding a single JSON value of at most maxSize bytes into v.
+// Unknown fields are rejected so misspelled options do not go unnoticed.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}, maxSize int64) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSize))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		logging.FromContext(r.Context()).Debug("Failed to decode request body", "error", err)
+		return decodeError(err, maxSize)
+	}
+
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidRequest, "Request body must contain a single JSON value")
+	}
+
+	return nil
+}
+
+// decodeError turns an error decoding a request body into the error reported to the client.
+func decodeError(err error, maxSize int64) *response.Error {
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return response.NewError(http.StatusRequestEntityTooLarge, response.CodePayloadTooLarge, fmt.Sprintf("Request body must not be larger than %d bytes", maxSize))
+	case errors.As(err, &syntaxErr):
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidRequest, fmt.Sprintf("Malformed JSON at offset %d", syntaxErr.Offset))
+	case errors.As(err, &typeErr):
+		return response.InvalidFields(response.FieldError{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return response.InvalidFields(response.FieldError{Field: field, Message: "unknown field"})
+	case errors.Is(err, io.EOF):
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidRequest, "Request body must not be empty")
+	default:
+		return response.NewError(http.StatusBadRequest, response.CodeInvalidRequest, "Malformed JSON")
+	}
+}
+
+// validateJob checks a submitted job against the language registry and the size limits.
+// Every invalid field is reported, each under its name prefixed with prefix.
+func validateJob(job models.Job, prefix string) []response.FieldError {
+	var fields []response.FieldError
+	invalid := func(field, format string, args ...interface{}) {
+		fields = append(fields, response.FieldError{Field: prefix + field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	language, ok := models.LookupLanguage(job.Language)
+	if job.Language == "" {
+		invalid("language", "is required")
+	} else if !ok {
+		invalid("language", "unsupported language %q", job.Language)
+	}
+
+	switch {
+	case len(job.Files) > 0:
+		if job.Code != "" {
+			invalid("code", "must be empty when files are given")
+		}
+		if err := security.ValidateFileTree(job.Files, job.Entrypoint); err != nil {
+			invalid("files", "%v", err)
+		}
+	case job.Code == "":
+		invalid("code", "is required")
+	case len(job.Code) > security.MaxCodeSize:
+		invalid("code", "must not be larger than %d bytes", security.MaxCodeSize)
+	}
+
+	if len(job.Stdin) > security.MaxStdinSize {
+		invalid("stdin", "must not be larger than %d bytes", security.MaxStdinSize)
+	}
+
+	if job.TimeLimit < 0 {
+		invalid("time_limit", "must not be negative")
+	} else if ok && job.TimeLimit > language.Timeout.Seconds() {
+		invalid("time_limit", "must not exceed %v seconds for %s", language.Timeout.Seconds(), job.Language)
+	}
+
+	if job.MemoryLimit < 0 {
+		invalid("memory_limit", "must not be negative")
+	} else if job.MemoryLimit > 0 && job.MemoryLimit < security.MinMemory {
+		invalid("memory_limit", "must be at least %d bytes", security.MinMemory)
+	} else if ok && job.MemoryLimit > language.Memory {
+		invalid("memory_limit", "must not exceed %d bytes for %s", language.Memory, job.Language)
+	}
+
+	if job.CallbackURL != "" {
+		if err := webhook.ValidateCallbackURL(job.CallbackURL, webhook.GetConfig().AllowedHosts); err != nil {
+			invalid("callback_url", "%v", err)
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"CodeXecutor/internal/response"
+	"CodeXecutor/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateJob(t *testing.T) {
+	valid := models.Job{Language: "python", Code: "print(input())", Stdin: "hi", TimeLimit: 1, MemoryLimit: 64 << 20}
+	assert.Empty(t, validateJob(valid, ""), "Valid job should pass")
+
+	invalid := models.Job{Language: "cobol", Stdin: strings.Repeat("x", 65<<10), TimeLimit: -1}
+	assert.Equal(t, []response.FieldError{
+		{Field: "jobs[2].language", Message: `unsupported language "cobol"`},
+		{Field: "jobs[2].code", Message: "is required"},
+		{Field: "jobs[2].stdin", Message: "must not be larger than 65536 bytes"},
+		{Field: "jobs[2].time_limit", Message: "must not be negative"},
+	}, validateJob(invalid, "jobs[2]."), "Every invalid field should be reported")
+
+	tooMuch := models.Job{Language: "python", Code: "print(1)", TimeLimit: 60, MemoryLimit: 1 << 30}
+	assert.Equal(t, []string{"time_limit", "memory_limit"}, fieldNames(validateJob(tooMuch, "")), "Limits above the language's should be rejected")
+}
+
+func TestDecodeJSON(t *testing.T) {
+	decode := func(body string, maxSize int64) *response.Error {
+		var submitted submission
+		r := httptest.NewRequest("POST", "/submit", strings.NewReader(body))
+		err := decodeJSON(httptest.NewRecorder(), r, &submitted, maxSize)
+		if err == nil {
+			return nil
+		}
+		return err.(*response.Error)
+	}
+
+	assert.Nil(t, decode(`{"language": "python", "code": "print(1)"}`, 1024))
+	assert.Equal(t, []response.FieldError{{Field: "lang", Message: "unknown field"}}, decode(`{"lang": "python"}`, 1024).Fields)
+	for _, field := range []string{"id", "tenant", "request_id", "batch_id", "trace_context", "cache_result", "interactive", "time"} {
+		assert.Equal(t, []response.FieldError{{Field: field, Message: "unknown field"}}, decode(`{"`+field+`": null}`, 1024).Fields, "Fields set by the server should be rejected")
+	}
+	assert.Equal(t, []response.FieldError{{Field: "time_limit", Message: "must be of type float64"}}, decode(`{"time_limit": "1s"}`, 1024).Fields)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, decode(`{"code": "`+strings.Repeat("x", 2048)+`"}`, 1024).Status)
+	assert.Equal(t, http.StatusBadRequest, decode(`{"code": "a"} {}`, 1024).Status)
+}
+
+func fieldNames(fields []response.FieldError) []string {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Field
+	}
+	return names
+}
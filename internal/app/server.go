@@ -3,22 +3,36 @@ package app
 import (
 	"CodeXecutor/internal/app/handler"
 	"CodeXecutor/internal/middleware"
+	"CodeXecutor/pkg/database"
+	"CodeXecutor/pkg/logging"
+	"CodeXecutor/pkg/metrics"
+	redisClient "CodeXecutor/pkg/redis"
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// shutdownTimeout bounds how long Stop waits for requests in progress, like clients waiting for results
+// or following events. Connections still open once it expires are closed, cancelling their requests.
+const shutdownTimeout = 10 * time.Second
+
 // Server represents the application server.
 type Server struct {
 	// Add server-related fields and dependencies here
+	addr       string
+	store      *redisClient.Store
+	db         database.Store
 	httpServer *http.Server
 }
 
-// NewServer initializes and returns a new Server instance.
-func NewServer() *Server {
-	return &Server{}
+// NewServer initializes and returns a new Server instance listening on addr, keeping jobs in store
+// and their history in db.
+func NewServer(addr string, store *redisClient.Store, db database.Store) *Server {
+	return &Server{addr: addr, store: store, db: db}
 }
 
 // Start starts the application server.
@@ -29,30 +43,70 @@ func (server *Server) Start() {
 	// Use the JSONMiddleware for all routes
 	router.Use(middleware.JSONMiddleware)
 
+	// Answer unknown routes in the same envelope as every other error
+	router.NotFoundHandler = middleware.RequestIDMiddleware(http.HandlerFunc(handler.HandleNotFound))
+	router.MethodNotAllowedHandler = middleware.RequestIDMiddleware(http.HandlerFunc(handler.HandleMethodNotAllowed))
+
+	// Give every request an ID, returned with every response
+	router.Use(middleware.RequestIDMiddleware)
+
+	// Continue the trace of the caller, or start one, for every request
+	router.Use(middleware.TracingMiddleware)
+
+	// Limit the request rate of every client address, even before it is authenticated
+	router.Use(middleware.RateLimitMiddleware(server.store))
+
+	// Require an API key and scope every request to the key's tenant
+	router.Use(middleware.APIKeyMiddleware(server.store))
+
+	// Limit the request rate of every tenant, across all of its addresses
+	router.Use(middleware.TenantRateLimitMiddleware(server.store))
+
 	// Define routes
-	router.HandleFunc("/submit", handler.HandleCodeSubmission).Methods("POST")
-	router.HandleFunc("/result", handler.HandleResult).Methods("GET")
+	jobs := handler.New(server.store, server.db)
+	quota := middleware.QuotaMiddleware(server.store)
+	router.Handle("/submit", quota(http.HandlerFunc(jobs.HandleCodeSubmission))).Methods("POST")
+	router.HandleFunc("/result", jobs.HandleResult).Methods("GET")
+	router.HandleFunc("/jobs", jobs.HandleListJobs).Methods("GET")
+	router.HandleFunc("/jobs/{id}", jobs.HandleJob).Methods("GET")
+	router.HandleFunc("/jobs/{id}", jobs.HandleCancelJob).Methods("DELETE")
+	router.HandleFunc("/jobs/{id}/events", jobs.HandleJobEvents).Methods("GET")
+	router.HandleFunc("/jobs/{id}/deliveries", jobs.HandleDeliveries).Methods("GET")
+	router.Handle("/sessions", quota(http.HandlerFunc(jobs.HandleSession))).Methods("GET")
+	router.Handle("/batches", quota(http.HandlerFunc(jobs.HandleBatchSubmission))).Methods("POST")
+	router.HandleFunc("/batches/{id}", jobs.HandleBatch).Methods("GET")
+	router.HandleFunc("/batches/{id}/results", jobs.HandleBatchResults).Methods("GET")
+
+	// Metrics and health checks are requested without an API key, so they are served next to the API rather than through its middleware
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", middleware.RequestIDMiddleware(http.HandlerFunc(handler.HandleHealth)))
+	mux.Handle("/readyz", middleware.RequestIDMiddleware(http.HandlerFunc(handler.HandleReady)))
+	mux.Handle("/", router)
 
 	// Create an HTTP server with the Gorilla Mux router
 	server.httpServer = &http.Server{
-		Addr:    "localhost:8080",
-		Handler: router,
+		Addr:    server.addr,
+		Handler: mux,
 	}
 
 	go func() {
-		if err := server.httpServer.ListenAndServe(); err != nil {
-			log.Fatalf("HTTP server error: %v", err)
+		if err := server.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("HTTP server error", "error", err)
 		}
 	}()
 
-	log.Println("Server started on localhost:8080")
+	slog.Info("Server started", "addr", server.httpServer.Addr)
 }
 
 // Stop gracefully stops the application server.
 func (server *Server) Stop() {
 	// Shutdown the HTTP server gracefully
-	if err := server.httpServer.Shutdown(context.Background()); err != nil {
-		log.Printf("Error during server shutdown: %v", err)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.httpServer.Shutdown(ctx); err != nil {
+		slog.Error("Error during server shutdown", "error", err)
+		server.httpServer.Close()
 	}
-	log.Println("shutting the server")
+	slog.Info("Server stopped")
 }
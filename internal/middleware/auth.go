@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"CodeXecutor/internal/response"
+	"CodeXecutor/pkg/logging"
+	redisClient "CodeXecutor/pkg/redis"
+	"CodeXecutor/pkg/security"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// APIKeyHeader carries the API key of a request.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyParam carries the API key for clients that cannot set headers, like EventSource and WebSocket in browsers.
+const APIKeyParam = "api_key"
+
+type tenantContextKey struct{}
+
+// APIKeyMiddleware authenticates requests by their API key and adds the key's tenant to the request context.
+// Requests without a valid key are rejected with 401 Unauthorized.
+func APIKeyMiddleware(store *redisClient.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant, err := security.LookupTenant(r.Context(), store, APIKey(r))
+			if errors.Is(err, security.ErrUnknownAPIKey) {
+				response.Fail(w, r, response.NewError(http.StatusUnauthorized, response.CodeUnauthorized, "Missing or invalid API key"))
+				return
+			} else if err != nil {
+				logging.FromContext(r.Context()).Error("Failed to look up API key", "error", err)
+				response.Fail(w, r, response.NewError(http.StatusServiceUnavailable, response.CodeUnavailable, "Failed to authenticate"))
+				return
+			}
+
+			ctx := WithTenant(r.Context(), tenant)
+			ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("tenant", tenant))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// APIKey returns the API key of a request, from the X-API-Key header, a bearer token or the api_key parameter.
+func APIKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	return r.URL.Query().Get(APIKeyParam)
+}
+
+// WithTenant returns a copy of ctx carrying the tenant of the request.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant added to the request context by APIKeyMiddleware.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
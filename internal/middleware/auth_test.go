@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKey(t *testing.T) {
+	r := httptest.NewRequest("GET", "/result?api_key=param-key", nil)
+	assert.Equal(t, "param-key", APIKey(r))
+
+	r.Header.Set("Authorization", "Bearer bearer-key")
+	assert.Equal(t, "bearer-key", APIKey(r))
+
+	r.Header.Set(APIKeyHeader, "header-key")
+	assert.Equal(t, "header-key", APIKey(r))
+}
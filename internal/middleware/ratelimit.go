@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"CodeXecutor/internal/response"
+	"CodeXecutor/pkg/logging"
+	"CodeXecutor/pkg/ratelimit"
+	redisClient "CodeXecutor/pkg/redis"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitMiddleware rejects clients making requests faster than the configured token bucket allows.
+// Clients are identified by their IP address only, as their API key has not been checked yet.
+func RateLimitMiddleware(store *redisClient.Store) func(http.Handler) http.Handler {
+	return rateLimitMiddleware(store, func(r *http.Request) (string, ratelimit.RateLimitConfig) {
+		return "ip:" + clientIP(r), ratelimit.GetConfig().RateLimit
+	})
+}
+
+// TenantRateLimitMiddleware rejects tenants making requests faster than their token bucket allows, whatever address they come from.
+// It has to run after APIKeyMiddleware.
+func TenantRateLimitMiddleware(store *redisClient.Store) func(http.Handler) http.Handler {
+	return rateLimitMiddleware(store, func(r *http.Request) (string, ratelimit.RateLimitConfig) {
+		return "tenant:" + TenantFromContext(r.Context()), ratelimit.GetConfig().TenantRateLimit
+	})
+}
+
+// rateLimitMiddleware limits requests with the token bucket of the client and settings bucket returns.
+func rateLimitMiddleware(store *redisClient.Store, bucket func(r *http.Request) (string, ratelimit.RateLimitConfig)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, config := bucket(r)
+
+			allowed, remaining, retryAfter, err := store.TakeToken(r.Context(), client, config.RequestsPerSecond, config.Burst)
+			if err != nil {
+				// Serve the request rather than failing every request while Redis is unavailable
+				logging.FromContext(r.Context()).Warn("Failed to check rate limit", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(config.Burst))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			if !allowed {
+				w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+				response.Fail(w, r, response.NewError(http.StatusTooManyRequests, response.CodeRateLimited, "Rate limit exceeded"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// QuotaMiddleware rejects submissions of tenants that used up their daily execution time.
+// It has to run after APIKeyMiddleware.
+func QuotaMiddleware(store *redisClient.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant := TenantFromContext(r.Context())
+			limit := ratelimit.GetConfig().Quota.DailyLimit(tenant)
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			now := time.Now()
+			used, err := store.GetExecutionSeconds(r.Context(), tenant, now)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Failed to get usage of tenant", "error", err)
+				response.Fail(w, r, response.NewError(http.StatusServiceUnavailable, response.CodeUnavailable, "Failed to check quota"))
+				return
+			}
+
+			reset := ratelimit.QuotaReset(now)
+			w.Header().Set("X-Quota-Limit", formatSeconds(limit))
+			w.Header().Set("X-Quota-Used", formatSeconds(used))
+			w.Header().Set("X-Quota-Remaining", formatSeconds(math.Max(0, limit-used)))
+			w.Header().Set("X-Quota-Reset", strconv.FormatInt(reset.Unix(), 10))
+			if used >= limit {
+				w.Header().Set("Retry-After", retryAfterSeconds(reset.Sub(now)))
+				message := fmt.Sprintf("Daily quota of %s execution seconds exceeded", formatSeconds(limit))
+				response.Fail(w, r, response.NewError(http.StatusTooManyRequests, response.CodeQuotaExceeded, message))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the IP address a request came from.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// retryAfterSeconds formats a delay for the Retry-After header, rounded up to whole seconds.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// formatSeconds formats execution seconds for the quota headers.
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', -1, 64)
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"CodeXecutor/pkg/config"
+	"CodeXecutor/pkg/ratelimit"
+	redisClient "CodeXecutor/pkg/redis"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitIgnoresAPIKey(t *testing.T) {
+	redisConfig := config.Default().Redis
+	redisConfig.MinIdleConns = 0
+	store, err := redisClient.NewStore(context.Background(), redisConfig)
+	if err != nil {
+		t.Fatalf("Error connecting to Redis: %v", err)
+	}
+	defer store.Close()
+
+	handler := RateLimitMiddleware(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// A client sending a different unchecked key with every request still shares the bucket of its address
+	limited := false
+	for i := 0; i <= ratelimit.GetConfig().RateLimit.Burst && !limited; i++ {
+		r := httptest.NewRequest("GET", "/result", nil)
+		r.RemoteAddr = "198.51.100.7:4000"
+		r.Header.Set(APIKeyHeader, "random-key-"+strconv.Itoa(i))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		limited = w.Code == http.StatusTooManyRequests
+	}
+	assert.True(t, limited, "Requests over the burst should be limited whatever API key they send")
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"CodeXecutor/internal/response"
+	"CodeXecutor/pkg/logging"
+	"CodeXecutor/utils"
+	"net/http"
+	"regexp"
+)
+
+// RequestIDHeader carries the ID of a request, chosen by the client or assigned by the server.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern restricts client-chosen request IDs to values that are safe to log and echo.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestIDMiddleware assigns every request an ID, returned in the X-Request-ID header and every response body.
+// A valid ID sent by the client is kept so requests can be traced across services.
+// The logger of the request context logs the ID with every line.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = utils.GenerateUniqueID()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		ctx := response.WithRequestID(r.Context(), requestID)
+		ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("request_id", requestID))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
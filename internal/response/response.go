@@ -0,0 +1,144 @@
+package response
+
+import (
+	"CodeXecutor/pkg/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Stable error codes, part of every error response so clients need not parse messages.
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeGone             = "gone"
+	CodeRateLimited      = "rate_limited"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeInternal         = "internal_error"
+	CodeUnavailable      = "service_unavailable"
+)
+
+// Envelope is the body of every response, holding either the data or the error of a request.
+type Envelope struct {
+	RequestID string      `json:"request_id"`
+	Data      interface{} `json:"data,omitempty"`
+	Error     *Error      `json:"error,omitempty"`
+}
+
+// FieldError describes what is wrong with a single field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a failed request, answered with its status, a stable code and the fields at fault.
+type Error struct {
+	Status  int          `json:"-"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return e.Message + ": " + strings.Join(messages, "; ")
+}
+
+// NewError returns an error answered with the given status and code.
+func NewError(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// InvalidFields returns the error of a request with invalid fields.
+func InvalidFields(fields ...FieldError) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: "Invalid request", Fields: fields}
+}
+
+// CodeForStatus returns the error code of a status, for errors raised outside the handlers.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeGone
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+
+	if status < http.StatusInternalServerError {
+		return CodeInvalidRequest
+	}
+	return CodeInternal
+}
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the ID of the request.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestID returns the ID of the request ctx belongs to.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// JSON responds with data in the envelope.
+func JSON(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	write(w, r, status, Envelope{RequestID: RequestID(r.Context()), Data: data})
+}
+
+// Fail responds with an error in the envelope. Errors other than *Error are reported as internal errors
+// without their message, which may reveal details of the server.
+func Fail(w http.ResponseWriter, r *http.Request, err error) {
+	var responseErr *Error
+	if !errors.As(err, &responseErr) {
+		logging.FromContext(r.Context()).Error("Internal error", "error", err)
+		responseErr = NewError(http.StatusInternalServerError, CodeInternal, "Internal server error")
+	}
+
+	write(w, r, responseErr.Status, Envelope{RequestID: RequestID(r.Context()), Error: responseErr})
+}
+
+// FailWithData responds with an error in the envelope, next to data detailing it.
+func FailWithData(w http.ResponseWriter, r *http.Request, err *Error, data interface{}) {
+	write(w, r, err.Status, Envelope{RequestID: RequestID(r.Context()), Data: data, Error: err})
+}
+
+func write(w http.ResponseWriter, r *http.Request, status int, envelope Envelope) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(envelope); err != nil {
+		logging.FromContext(r.Context()).Warn("Failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,31 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFail(t *testing.T) {
+	r := httptest.NewRequest("GET", "/result", nil)
+	r = r.WithContext(WithRequestID(r.Context(), "request-1"))
+
+	w := httptest.NewRecorder()
+	Fail(w, r, InvalidFields(FieldError{Field: "wait", Message: "must not be negative"}))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"request_id": "request-1", "error": {"code": "invalid_request", "message": "Invalid request", "fields": [{"field": "wait", "message": "must not be negative"}]}}`, w.Body.String())
+
+	// Unexpected errors do not leak their message
+	w = httptest.NewRecorder()
+	Fail(w, r, errors.New("dial tcp 10.0.0.1:6379: connection refused"))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var envelope Envelope
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope))
+	assert.Equal(t, CodeInternal, envelope.Error.Code)
+	assert.Equal(t, "Internal server error", envelope.Error.Message)
+}
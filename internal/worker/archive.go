@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"CodeXecutor/models"
+	"archive/tar"
+	"bytes"
+	"context"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// WorkDir is the working directory of the containers, holding the files of the job.
+const WorkDir = "/workspace"
+
+// InputDir holds the input of a job, outside the working directory so it cannot clash with the job's files.
+const InputDir = "/input"
+
+// stdinFile is the file in InputDir redirected to the program's stdin.
+const stdinFile = "stdin"
+
+// copyFiles copies a file tree into a directory of a created container.
+// The files travel as a tar archive through the Docker API rather than on the command line.
+func (w *Worker) copyFiles(ctx context.Context, containerID, dir string, files map[string]string) error {
+	archive, err := buildArchive(dir, files)
+	if err != nil {
+		return err
+	}
+
+	return w.client.CopyToContainer(ctx, containerID, "/", archive, types.CopyToContainerOptions{})
+}
+
+// buildArchive packs a file tree into a tar archive rooted at the absolute directory dir.
+func buildArchive(dir string, files map[string]string) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	modTime := time.Now()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	written := map[string]bool{}
+	for _, name := range names {
+		// Directories are written before the files they contain
+		for _, parent := range parentDirs(name) {
+			if written[parent] {
+				continue
+			}
+			written[parent] = true
+
+			header := &tar.Header{Typeflag: tar.TypeDir, Name: path.Join(dir[1:], parent) + "/", Mode: 0755, ModTime: modTime}
+			if err := tw.WriteHeader(header); err != nil {
+				return nil, err
+			}
+		}
+
+		content := files[name]
+		header := &tar.Header{Typeflag: tar.TypeReg, Name: path.Join(dir[1:], name), Mode: 0644, Size: int64(len(content)), ModTime: modTime}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// parentDirs returns the directories containing a file, from the outermost "." inwards.
+func parentDirs(name string) []string {
+	dirs := []string{}
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+	}
+	return append([]string{"."}, dirs...)
+}
+
+// withStdin wraps a command so it reads its stdin from the input file copied by copyFiles.
+func withStdin(cmd []string) []string {
+	return append([]string{"sh", "-c", `exec "$@" < ` + path.Join(InputDir, stdinFile), "sh"}, cmd...)
+}
+
+// runCommand returns the command of a run's container, reading its stdin from the input file if the job has input.
+func runCommand(config models.DockerConfig) []string {
+	if config.Stdin == "" {
+		return config.Cmd
+	}
+	return withStdin(config.Cmd)
+}
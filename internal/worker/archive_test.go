@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"CodeXecutor/models"
+	"archive/tar"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildArchive(t *testing.T) {
+	files := map[string]string{
+		"main.py":         "import pkg.util",
+		"pkg/util.py":     "x = 1",
+		"pkg/sub/deep.py": "y = 2",
+		"pkg/__init__.py": "",
+	}
+
+	archive, err := buildArchive(WorkDir, files)
+	assert.NoError(t, err, "Error building archive")
+
+	var names []string
+	contents := map[string]string{}
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err, "Error reading archive")
+		names = append(names, header.Name)
+
+		data, err := io.ReadAll(tr)
+		assert.NoError(t, err, "Error reading archive")
+		contents[header.Name] = string(data)
+	}
+
+	// Every directory precedes its files, everything lives in the working directory
+	assert.Equal(t, []string{
+		"workspace/",
+		"workspace/main.py",
+		"workspace/pkg/",
+		"workspace/pkg/__init__.py",
+		"workspace/pkg/sub/",
+		"workspace/pkg/sub/deep.py",
+		"workspace/pkg/util.py",
+	}, names, "Unexpected archive entries")
+	assert.Equal(t, "x = 1", contents["workspace/pkg/util.py"], "File content should be preserved")
+}
+
+func TestRunCommand(t *testing.T) {
+	cmd := []string{"python", "main.py"}
+
+	tests := []struct {
+		name  string
+		stdin string
+		want  []string
+	}{
+		{"without stdin", "", []string{"python", "main.py"}},
+		{"with stdin", "1 2\n", []string{"sh", "-c", `exec "$@" < /input/stdin`, "sh", "python", "main.py"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, runCommand(models.DockerConfig{Cmd: cmd, Stdin: tt.stdin}), "Only a job with input should read it from the input file")
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// jobRegistry tracks the jobs running in a pool so they can be cancelled.
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]context.CancelFunc
+}
+
+func newJobRegistry() *jobRegistry {
+	return &jobRegistry{jobs: make(map[string]context.CancelFunc)}
+}
+
+func (r *jobRegistry) add(jobKey string, cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[jobKey] = cancel
+}
+
+func (r *jobRegistry) remove(jobKey string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.jobs, jobKey)
+}
+
+// cancel cancels a running job and reports whether it was running in this pool.
+func (r *jobRegistry) cancel(jobKey string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cancel, ok := r.jobs[jobKey]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// ListenCancellations cancels the jobs of the pool announced on the cancellation channel.
+// Jobs owned by workers in other processes are ignored here and cancelled by their own pool.
+func ListenCancellations(wp *WorkerPool) {
+	pubsub := wp.store.SubscribeCancellations(wp.jobsCtx)
+	defer pubsub.Close()
+
+	for {
+		select {
+		case msg, ok := <-pubsub.Channel():
+			if !ok {
+				return
+			}
+			if wp.running.cancel(msg.Payload) {
+				slog.Info("Cancelling job", "job_key", msg.Payload)
+			}
+		case <-wp.jobsCtx.Done():
+			return
+		}
+	}
+}
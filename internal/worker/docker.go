@@ -1,18 +1,30 @@
 package worker
 
 import (
+	"CodeXecutor/pkg/logging"
 	"context"
-	"log"
-	"time"
+	"fmt"
+	"io"
+	"log/slog"
 
 	"CodeXecutor/models"
+	"CodeXecutor/pkg/metrics"
+	redisClient "CodeXecutor/pkg/redis"
+	"CodeXecutor/pkg/tracing"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // GenerateAndStartContainer dynamically generates a Docker container for code execution.
-func (w *Worker) GenerateAndStartContainer(config models.DockerConfig) (string, error) {
+// Cancelling ctx stops waiting for the container, which is left for StopAndRemoveContainer to kill.
+// Failures are logged with the logger of ctx.
+func (w *Worker) GenerateAndStartContainer(ctx context.Context, config models.DockerConfig) (string, error) {
+	logger := logging.FromContext(ctx)
+
 	containerConfig := &container.Config{
 		Image:        config.Image,
 		AttachStdin:  true,
@@ -21,63 +33,147 @@ func (w *Worker) GenerateAndStartContainer(config models.DockerConfig) (string,
 		Tty:          true,
 		OpenStdin:    true,
 		StdinOnce:    true,
-		Cmd:          []string{config.Language, "-c", config.Code},
+		Cmd:          runCommand(config),
+		WorkingDir:   WorkDir,
 	}
 
-	hostConfig := &container.HostConfig{}
+	hostConfig := &container.HostConfig{
+		Resources: container.Resources{
+			Memory: config.Memory,
+		},
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
 	defer cancel()
 
-	resp, err := w.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, config.ID)
+	spanCtx, span := tracing.Start(ctx, "docker.create", trace.WithAttributes(attribute.String("container.image.name", config.Image)))
+	resp, err := w.client.ContainerCreate(spanCtx, containerConfig, hostConfig, nil, nil, config.ID)
+	tracing.End(span, err)
 	if err != nil {
-		log.Printf("Error creating container: %v\n", err)
+		logger.Error("Error creating container", "error", err)
+		metrics.ContainerFailures.WithLabelValues(metrics.StageCreate).Inc()
 		return "", err
 	}
 
-	if err := w.client.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
-		log.Printf("Error starting container: %v\n", err)
+	logger = logger.With("container_id", resp.ID)
+	logger.Debug("Created container", "image", config.Image)
+
+	if len(config.Files) > 0 {
+		spanCtx, span := tracing.Start(ctx, "docker.copy", trace.WithAttributes(attribute.String("container.id", resp.ID), attribute.Int("files", len(config.Files))))
+		err := w.copyFiles(spanCtx, resp.ID, WorkDir, config.Files)
+		tracing.End(span, err)
+		if err != nil {
+			logger.Error("Error copying files to container", "error", err)
+			metrics.ContainerFailures.WithLabelValues(metrics.StageCopy).Inc()
+			return resp.ID, err
+		}
+	}
+
+	if config.Stdin != "" {
+		spanCtx, span := tracing.Start(ctx, "docker.copy", trace.WithAttributes(attribute.String("container.id", resp.ID), attribute.Int("files", 1)))
+		err := w.copyFiles(spanCtx, resp.ID, InputDir, map[string]string{stdinFile: config.Stdin})
+		tracing.End(span, err)
+		if err != nil {
+			logger.Error("Error copying stdin to container", "error", err)
+			metrics.ContainerFailures.WithLabelValues(metrics.StageCopy).Inc()
+			return resp.ID, err
+		}
+	}
+
+	spanCtx, span = tracing.Start(ctx, "docker.start", trace.WithAttributes(attribute.String("container.id", resp.ID)))
+	err = w.client.ContainerStart(spanCtx, resp.ID, types.ContainerStartOptions{})
+	tracing.End(span, err)
+	if err != nil {
+		logger.Error("Error starting container", "error", err)
+		metrics.ContainerFailures.WithLabelValues(metrics.StageStart).Inc()
 		return resp.ID, err
 	}
 
+	// Stream the output to clients following the job while it runs
+	streamed := make(chan struct{})
+	go func() {
+		defer close(streamed)
+		w.streamLogs(ctx, logger, config.Key, resp.ID, containerConfig.Tty)
+	}()
+
 	// Wait for the container to finish
-	waitResultCh, errCh := w.client.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	spanCtx, span = tracing.Start(ctx, "docker.wait", trace.WithAttributes(attribute.String("container.id", resp.ID)))
+	defer span.End()
+	waitResultCh, errCh := w.client.ContainerWait(spanCtx, resp.ID, container.WaitConditionNotRunning)
 	select {
 	case waitResult := <-waitResultCh:
+		// The log stream ends right after the container, so the result follows all of the output
+		<-streamed
 		if waitResult.StatusCode != 0 {
 			return resp.ID, err
 			// return resp.ID, fmt.Errorf("container exited with non-zero status code: %d", waitResult.StatusCode)
 		}
 	case err := <-errCh:
 		if err != nil {
-			log.Printf("Error waiting for container to finish: %v\n", err)
+			logger.Error("Error waiting for container to finish", "error", err)
 
 			return resp.ID, err
 		}
 	case <-ctx.Done():
-		log.Printf("Time out.\n")
+		logger.Info("Stopped waiting for container", "reason", ctx.Err())
 		return resp.ID, ctx.Err()
 	}
 
 	return resp.ID, nil
 }
 
+// streamLogs follows the output of a running container and appends it to the event stream of the job until ctx is done.
+func (w *Worker) streamLogs(ctx context.Context, logger *slog.Logger, jobKey, containerID string, tty bool) {
+	out, err := w.client.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: true})
+	if err != nil {
+		logger.Error("Error following container logs", "error", err)
+		return
+	}
+	defer out.Close()
+
+	stdout := &eventWriter{ctx: ctx, store: w.store, jobKey: jobKey, eventType: models.EventStdout}
+	stderr := &eventWriter{ctx: ctx, store: w.store, jobKey: jobKey, eventType: models.EventStderr}
+
+	// A TTY merges stderr into stdout, otherwise both streams are multiplexed in the logs
+	if tty {
+		_, err = io.Copy(stdout, out)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, out)
+	}
+	if err != nil {
+		logger.Error("Error streaming container logs", "error", err)
+	}
+}
+
+// eventWriter appends everything written to it as output events of a job.
+type eventWriter struct {
+	ctx       context.Context
+	store     *redisClient.Store
+	jobKey    string
+	eventType string
+}
+
+func (ew *eventWriter) Write(p []byte) (int, error) {
+	if err := ew.store.AppendEvent(ew.ctx, ew.jobKey, models.Event{Type: ew.eventType, Data: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
 // StopAndRemoveContainer stops and removes a Docker container.
-func (w *Worker) StopAndRemoveContainer(containerID string) error {
+func (w *Worker) StopAndRemoveContainer(ctx context.Context, containerID string) error {
 	timeout := int(0)
 
 	stopOptions := container.StopOptions{
 		Timeout: &timeout,
 	}
 
-	if err := w.client.ContainerStop(w.ctx, containerID, stopOptions); err != nil {
-		log.Printf("Error stopping container: %v\n", err)
-		return err
+	if err := w.client.ContainerStop(ctx, containerID, stopOptions); err != nil {
+		return fmt.Errorf("stopping container: %w", err)
 	}
 
-	if err := w.client.ContainerRemove(w.ctx, containerID, types.ContainerRemoveOptions{}); err != nil {
-		log.Printf("Error removing container: %v\n", err)
-		return err
+	if err := w.client.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{}); err != nil {
+		return fmt.Errorf("removing container: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"CodeXecutor/models"
+	"CodeXecutor/pkg/health"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// registerChecks adds the dependencies of the pool and the pool itself to the readiness checks.
+func (wp *WorkerPool) registerChecks() {
+	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		health.Register("docker", func(context.Context) error { return err })
+		health.Register("images", func(context.Context) error { return err })
+	} else {
+		health.Register("docker", func(ctx context.Context) error {
+			_, err := dockerClient.Ping(ctx)
+			return err
+		})
+		health.Register("images", func(ctx context.Context) error { return checkImages(ctx, dockerClient) })
+	}
+
+	health.Register("redis", wp.store.Ping)
+	health.Register("workers", func(context.Context) error { return wp.check() })
+}
+
+// checkImages checks that the images of all supported languages are present, naming the missing ones.
+func checkImages(ctx context.Context, dockerClient *client.Client) error {
+	var missing []string
+	for _, language := range models.SupportedLanguages() {
+		if _, _, err := dockerClient.ImageInspectWithRaw(ctx, language.Image); client.IsErrNotFound(err) {
+			missing = append(missing, language.Image)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("missing images: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// check reports whether the pool takes jobs off the queue and has workers to run them.
+func (wp *WorkerPool) check() error {
+	switch {
+	case wp.ctx.Err() != nil:
+		return errors.New("stopped")
+	case !wp.pulling.Load():
+		return errors.New("not pulling jobs from the queue")
+	case int(wp.size.Load()) == 0:
+		return errors.New("no workers")
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"sync"
+)
+
+// Limits bounds the executions a worker pool runs at the same time.
+type Limits struct {
+	// MaxConcurrent caps the concurrent executions per language.
+	// Languages without a positive entry are only bounded by the number of workers.
+	MaxConcurrent map[string]int
+	// MemoryBudget caps the memory reserved by all running containers, in bytes.
+	// Zero disables the budget.
+	MemoryBudget int64
+}
+
+// Limiter admits jobs while their language and the pool have capacity left.
+type Limiter struct {
+	mu       sync.Mutex
+	limits   Limits
+	running  map[string]int
+	memory   int64
+	released chan struct{} // closed and replaced whenever capacity is released
+}
+
+// NewLimiter creates a Limiter enforcing the given limits.
+func NewLimiter(limits Limits) *Limiter {
+	return &Limiter{
+		limits:   limits,
+		running:  make(map[string]int),
+		released: make(chan struct{}),
+	}
+}
+
+// Acquire blocks until the job can run within the limits or the context is done.
+func (l *Limiter) Acquire(ctx context.Context, job models.Job) error {
+	for {
+		released := l.Released()
+		if l.TryAcquire(job) {
+			return nil
+		}
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// TryAcquire reserves capacity for the job if it can run within the limits, and reports whether it did.
+func (l *Limiter) TryAcquire(job models.Job) bool {
+	memory := reservedMemory(job)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.admit(job.Language, memory) {
+		return false
+	}
+	l.running[job.Language]++
+	l.memory += memory
+	return true
+}
+
+// Available returns the languages a job could start in now, assuming it needs the memory limit of its
+// language. Languages that are not configured are available, their jobs need no capacity to fail.
+func (l *Limiter) Available(languages []string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var available []string
+	for _, name := range languages {
+		language, _ := models.LookupLanguage(name)
+		if l.admit(name, language.Memory) {
+			available = append(available, name)
+		}
+	}
+	return available
+}
+
+// Released returns a channel closed the next time capacity is released or the limits change.
+func (l *Limiter) Released() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.released
+}
+
+// Release returns the capacity held by a job acquired with Acquire.
+func (l *Limiter) Release(job models.Job) {
+	memory := reservedMemory(job)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.running[job.Language]--
+	l.memory -= memory
+
+	// Wake up every waiting job so each can check its own limits again
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// SetLimits replaces the limits. Running jobs keep their capacity, waiting jobs are admitted under the new limits.
+func (l *Limiter) SetLimits(limits Limits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limits = limits
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// reservedMemory returns the memory a job reserves, from the settings it was admitted with.
+func reservedMemory(job models.Job) int64 {
+	language, _ := job.LanguageSettings()
+	_, memory := job.Limits(language)
+	return memory
+}
+
+// admit reports whether a job can start. The caller must hold l.mu.
+func (l *Limiter) admit(language string, memory int64) bool {
+	if max := l.limits.MaxConcurrent[language]; max > 0 && l.running[language] >= max {
+		return false
+	}
+
+	// A job larger than the whole budget is still admitted once nothing else runs
+	if l.limits.MemoryBudget > 0 && l.memory > 0 && l.memory+memory > l.limits.MemoryBudget {
+		return false
+	}
+
+	return true
+}
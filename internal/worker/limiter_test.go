@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterLanguageLimit(t *testing.T) {
+	limiter := NewLimiter(Limits{MaxConcurrent: map[string]int{"java": 1}})
+	java := models.Job{ID: "1", Language: "java"}
+	python := models.Job{ID: "2", Language: "python"}
+
+	assert.NoError(t, limiter.Acquire(context.Background(), java), "First java job should be admitted")
+
+	// A second java job has to wait for the first one
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Acquire(ctx, java), context.DeadlineExceeded, "Second java job should wait")
+
+	// Other languages are not blocked by the java limit
+	assert.NoError(t, limiter.Acquire(context.Background(), python), "Python job should be admitted")
+
+	// Releasing the java job admits the waiting one
+	acquired := make(chan error)
+	go func() { acquired <- limiter.Acquire(context.Background(), java) }()
+	limiter.Release(java)
+	assert.NoError(t, <-acquired, "Waiting java job should be admitted after release")
+}
+
+func TestLimiterMemoryBudget(t *testing.T) {
+	memory := models.Languages["java"].Memory
+	limiter := NewLimiter(Limits{MemoryBudget: memory})
+	job := models.Job{ID: "1", Language: "java"}
+
+	assert.NoError(t, limiter.Acquire(context.Background(), job), "Job within the budget should be admitted")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Acquire(ctx, job), context.DeadlineExceeded, "Job over the budget should wait")
+
+	limiter.Release(job)
+	assert.NoError(t, limiter.Acquire(context.Background(), job), "Job should be admitted once memory is released")
+}
+
+func TestLimiterSetLimits(t *testing.T) {
+	limiter := NewLimiter(Limits{MaxConcurrent: map[string]int{"java": 1}})
+	language := models.Languages["java"]
+	java := models.Job{ID: "1", Language: "java", Settings: &language}
+
+	assert.NoError(t, limiter.Acquire(context.Background(), java), "First java job should be admitted")
+
+	// Raising the limit admits the waiting job
+	acquired := make(chan error)
+	go func() { acquired <- limiter.Acquire(context.Background(), java) }()
+	limiter.SetLimits(Limits{MaxConcurrent: map[string]int{"java": 2}})
+	assert.NoError(t, <-acquired, "Waiting java job should be admitted under the new limit")
+
+	// Jobs release the memory they were admitted with, whatever the language is reconfigured to
+	changed := language
+	changed.Memory *= 2
+	models.SetLanguages(map[string]models.Language{"java": changed})
+	defer models.SetLanguages(models.Languages)
+	limiter.Release(java)
+	limiter.Release(java)
+	assert.Zero(t, limiter.memory, "All reserved memory should be released")
+}
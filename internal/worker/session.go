@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"CodeXecutor/pkg/logging"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"CodeXecutor/models"
+	"CodeXecutor/pkg/metrics"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/redis/go-redis/v9"
+)
+
+// RunSession runs an interactive job in a container attached to a TTY.
+// Input queued for the job is written to the container's stdin and the output is appended to the job's events,
+// so the client can be served by any process. It returns once the program exits, the session goes without
+// input or output for config.Idle or it reaches its maximum length, config.Timeout.
+func (w *Worker) RunSession(ctx context.Context, config models.DockerConfig) (string, error) {
+	if len(config.Cmd) == 0 {
+		return "", fmt.Errorf("no interactive interpreter for language %s", config.Language)
+	}
+	logger := logging.FromContext(ctx)
+
+	containerConfig := &container.Config{
+		Image:        config.Image,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          true,
+		OpenStdin:    true,
+		Cmd:          config.Cmd,
+		WorkingDir:   WorkDir,
+	}
+
+	hostConfig := &container.HostConfig{
+		Resources: container.Resources{
+			Memory: config.Memory,
+		},
+	}
+
+	errSessionIdle := fmt.Errorf("session closed after %v without activity", config.Idle)
+	errSessionTooLong := fmt.Errorf("session closed after reaching the maximum length of %v", config.Timeout)
+
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+
+	resp, err := w.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, config.ID)
+	if err != nil {
+		logger.Error("Error creating container", "error", err)
+		metrics.ContainerFailures.WithLabelValues(metrics.StageCreate).Inc()
+		return "", err
+	}
+
+	logger = logger.With("container_id", resp.ID)
+
+	// Attach before starting so no output is missed
+	attach, err := w.client.ContainerAttach(ctx, resp.ID, types.ContainerAttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		logger.Error("Error attaching to container", "error", err)
+		return resp.ID, err
+	}
+	defer attach.Close()
+
+	if len(config.Files) > 0 {
+		if err := w.copyFiles(ctx, resp.ID, WorkDir, config.Files); err != nil {
+			logger.Error("Error copying files to container", "error", err)
+			metrics.ContainerFailures.WithLabelValues(metrics.StageCopy).Inc()
+			return resp.ID, err
+		}
+	}
+
+	if err := w.client.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		logger.Error("Error starting container", "error", err)
+		metrics.ContainerFailures.WithLabelValues(metrics.StageStart).Inc()
+		return resp.ID, err
+	}
+
+	var lastActivity atomic.Int64
+	lastActivity.Store(time.Now().UnixNano())
+
+	// Relay the output, the TTY merges stderr into stdout
+	go func() {
+		output := &activityWriter{
+			next:         &eventWriter{ctx: ctx, store: w.store, jobKey: config.Key, eventType: models.EventStdout},
+			lastActivity: &lastActivity,
+		}
+		if _, err := io.Copy(output, attach.Reader); err != nil {
+			logger.Warn("Error relaying session output", "error", err)
+		}
+	}()
+
+	// Relay the input queued by the client
+	go func() {
+		for ctx.Err() == nil {
+			data, err := w.store.PopStdin(ctx, config.Key, time.Second)
+			if errors.Is(err, redis.Nil) {
+				continue
+			} else if err != nil {
+				if ctx.Err() == nil {
+					logger.Error("Error reading session input", "error", err)
+				}
+				return
+			}
+
+			lastActivity.Store(time.Now().UnixNano())
+			if _, err := attach.Conn.Write([]byte(data)); err != nil {
+				logger.Warn("Error writing session input", "error", err)
+				return
+			}
+		}
+	}()
+
+	idleCheck := time.NewTicker(min(time.Second, config.Idle/2))
+	defer idleCheck.Stop()
+
+	// Wait for the program to exit or the session to time out
+	waitResultCh, errCh := w.client.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	for {
+		select {
+		case <-waitResultCh:
+			return resp.ID, nil
+		case err := <-errCh:
+			if errors.Is(err, context.DeadlineExceeded) {
+				return resp.ID, errSessionTooLong
+			}
+			logger.Error("Error waiting for container to finish", "error", err)
+			return resp.ID, err
+		case <-idleCheck.C:
+			if time.Since(time.Unix(0, lastActivity.Load())) > config.Idle {
+				return resp.ID, errSessionIdle
+			}
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return resp.ID, errSessionTooLong
+			}
+			return resp.ID, ctx.Err()
+		}
+	}
+}
+
+// activityWriter records the time of every write before passing it on.
+type activityWriter struct {
+	next         io.Writer
+	lastActivity *atomic.Int64
+}
+
+func (aw *activityWriter) Write(p []byte) (int, error) {
+	aw.lastActivity.Store(time.Now().UnixNano())
+	return aw.next.Write(p)
+}
@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"CodeXecutor/models"
+	"CodeXecutor/utils"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startSession runs a session in a fake container that echoes its input.
+func startSession(t *testing.T, w *Worker, config models.DockerConfig) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := w.RunSession(context.Background(), config)
+		done <- err
+	}()
+	return done
+}
+
+func sessionConfig(timeout, idle time.Duration) models.DockerConfig {
+	return models.DockerConfig{
+		ID:      "session",
+		Key:     models.JobKey("test", utils.GenerateUniqueID()),
+		Image:   "python:3.12",
+		Cmd:     []string{"python"},
+		Timeout: timeout,
+		Idle:    idle,
+	}
+}
+
+func TestRunSessionRelaysInputAndOutput(t *testing.T) {
+	store := newTestStore(t)
+	w := &Worker{client: newFakeDocker(t), store: store}
+	config := sessionConfig(10*time.Second, time.Second)
+	done := startSession(t, w, config)
+
+	assert.NoError(t, store.PushStdin(context.Background(), config.Key, "print(1)\n"))
+
+	var output strings.Builder
+	lastID := "0"
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(output.String(), "print(1)\n") && time.Now().Before(deadline) {
+		events, err := store.ReadEvents(context.Background(), config.Key, lastID, 500*time.Millisecond)
+		if !assert.NoError(t, err) {
+			break
+		}
+		for _, event := range events {
+			assert.Equal(t, models.EventStdout, event.Type)
+			output.WriteString(event.Data)
+			lastID = event.ID
+		}
+	}
+	assert.Equal(t, "print(1)\n", output.String(), "The input should be relayed to the container and its output back")
+
+	select {
+	case err := <-done:
+		assert.EqualError(t, err, "session closed after 1s without activity")
+	case <-time.After(5 * time.Second):
+		t.Fatal("The idle session was not closed")
+	}
+}
+
+func TestRunSessionIdleTimeout(t *testing.T) {
+	w := &Worker{client: newFakeDocker(t), store: newTestStore(t)}
+	start := time.Now()
+	done := startSession(t, w, sessionConfig(10*time.Second, 300*time.Millisecond))
+
+	select {
+	case err := <-done:
+		assert.EqualError(t, err, "session closed after 300ms without activity")
+		assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Fatal("The idle session was not closed")
+	}
+}
+
+func TestRunSessionMaxLength(t *testing.T) {
+	w := &Worker{client: newFakeDocker(t), store: newTestStore(t)}
+	done := startSession(t, w, sessionConfig(300*time.Millisecond, 10*time.Second))
+
+	select {
+	case err := <-done:
+		assert.EqualError(t, err, "session closed after reaching the maximum length of 300ms")
+	case <-time.After(5 * time.Second):
+		t.Fatal("The session outlived its maximum length")
+	}
+}
@@ -2,35 +2,56 @@ package worker
 
 import (
 	"CodeXecutor/models"
+	appConfig "CodeXecutor/pkg/config"
+	"CodeXecutor/pkg/database"
+	"CodeXecutor/pkg/logging"
+	"CodeXecutor/pkg/metrics"
 	redisClient "CodeXecutor/pkg/redis"
+	"CodeXecutor/pkg/tracing"
+	"CodeXecutor/pkg/webhook"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// cleanupTimeout bounds recording the result of a job and removing its container,
+// which happen even if the job was cancelled or the pool is stopping.
+const cleanupTimeout = 30 * time.Second
+
 // Worker represents a worker that handles code compilation jobs.
 type Worker struct {
-	ctx      context.Context
+	ctx      context.Context // cancelled when the pool stops, ending the job being handled
+	id       int
+	log      *slog.Logger // logs with the worker's ID
 	jobQueue <-chan models.Job
+	limiter  *Limiter
+	running  *jobRegistry
 	client   *client.Client
+	store    *redisClient.Store
+	db       database.Store
+	stop     chan struct{} // closed to stop the worker once its current job is handled
 	// Add other worker-related fields here
 }
 
-// NewWorker creates a new Worker instance.
-func NewWorker(jobQueue <-chan models.Job) *Worker {
-	ctx := context.Background()
+// NewWorker creates a new Worker instance, keeping the state of its jobs in store and their history in db.
+// The jobs run in contexts derived from ctx.
+func NewWorker(ctx context.Context, id int, jobQueue <-chan models.Job, limiter *Limiter, running *jobRegistry, store *redisClient.Store, db database.Store) *Worker {
 	dockerClient, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
 		return nil
 	}
-	return &Worker{ctx: ctx, jobQueue: jobQueue, client: dockerClient}
+	return &Worker{ctx: ctx, id: id, log: slog.With("worker_id", id), jobQueue: jobQueue, limiter: limiter, running: running, client: dockerClient, store: store, db: db, stop: make(chan struct{})}
 }
 
 // Start starts the worker to handle jobs.
@@ -46,6 +67,10 @@ func (w *Worker) Start(wg *sync.WaitGroup) {
 			}
 
 			w.handleJob(job)
+			w.limiter.Release(job)
+
+		case <-w.stop:
+			return
 
 		case <-w.ctx.Done():
 			{
@@ -56,69 +81,226 @@ func (w *Worker) Start(wg *sync.WaitGroup) {
 	}
 }
 
+// Stop stops the worker. A job it is handling runs to completion first.
+func (w *Worker) Stop() {
+	close(w.stop)
+}
+
+// handleJob runs a job and stores its result.
+// Everything logged for the job carries its ID, the ID of the request that submitted it and the worker's ID,
+// and its span continues the trace of the request. The job is stopped when it is cancelled or the pool stops,
+// its result is still recorded and its container removed.
 func (w *Worker) handleJob(job models.Job) {
-	// Map programming languages to corresponding Docker images
-	languageToImage := map[string]string{
-		"cpp":    "gcc:10.3",
-		"python": "python:3.9",
-		"java":   "openjdk:11.0.12",
-		"node":   "node:14.17",
-		"golang": "golang:1.21",
-		// Add more languages and their corresponding images as needed
-	}
-
-	// Check if the provided language is supported
-	image, ok := languageToImage[job.Language]
+	jobCtx := logging.WithLogger(w.ctx, w.log.With("job_id", job.ID, "tenant", job.Tenant, "request_id", job.RequestID))
+	jobCtx, span := tracing.Start(tracing.Extract(jobCtx, job), "execute",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("job.id", job.ID), attribute.String("job.language", job.Language), attribute.Int("worker.id", w.id)),
+	)
+	defer span.End()
+	jobCtx = tracing.WithTraceID(jobCtx)
+	logger := logging.FromContext(jobCtx)
+
+	metrics.ActiveWorkers.Inc()
+	defer metrics.ActiveWorkers.Dec()
+	if !job.DequeuedAt.IsZero() {
+		metrics.StartLatency.WithLabelValues(job.Language).Observe(time.Since(job.DequeuedAt).Seconds())
+	}
+
+	// Skip jobs that were cancelled while waiting for a worker
+	if cancelled, err := w.store.IsCancelled(jobCtx, job.Key()); err != nil {
+		logger.Error("Error checking cancellation", "error", err)
+	} else if cancelled {
+		logger.Info("Job cancelled before it started")
+		w.saveResult(jobCtx, job, models.CompilationResult{Status: models.StatusCancelled, ExitCode: -1})
+		return
+	}
+
+	// Check if the provided language is supported, with the settings it was admitted with
+	language, ok := job.LanguageSettings()
 	if !ok {
-		log.Printf("Unsupported programming language: %s\n", job.Language)
-		// Handle the error appropriately
+		// The language was removed by a reload while the job was queued
+		logger.Error("Unsupported programming language", "language", job.Language)
+		w.saveResult(jobCtx, job, models.CompilationResult{Status: models.StatusCompleted, ExitCode: -1, Error: fmt.Errorf("language %q is no longer supported", job.Language)})
 		return
 	}
 
-	containerID, err := w.GenerateAndStartContainer(models.DockerConfig{
+	// Register the job so a cancellation request can stop it
+	ctx, cancel := context.WithCancel(jobCtx)
+	defer cancel()
+	w.running.add(job.Key(), cancel)
+	defer w.running.remove(job.Key())
+
+	if err := w.store.SetJobStatus(jobCtx, job.Key(), models.StatusRunning); err != nil {
+		logger.Error("Error setting status", "error", err)
+	}
+	if err := w.db.SetStatus(ctx, job.Tenant, job.ID, models.StatusRunning); err != nil {
+		logger.Error("Error persisting status", "error", err)
+	}
+	logger.Info("Job started", "language", job.Language, "interactive", job.Interactive)
+
+	files, entrypoint := sourceFiles(job, language)
+	timeout, memory := job.Limits(language)
+	config := models.DockerConfig{
 		ID:       job.ID,
-		Image:    image,
+		Key:      job.Key(),
+		Image:    language.Image,
 		Language: job.Language,
-		Code:     job.Code,
-	})
+		Memory:   memory,
+		Timeout:  timeout,
+		Stdin:    job.Stdin,
+		Cmd:      command(job, language, entrypoint),
+		Files:    files,
+	}
+	if job.Interactive {
+		// A session waits for its user, so it is bounded by the session limits instead of the run time limit
+		limits := appConfig.Get().Limits
+		config.Timeout, config.Idle = limits.SessionMaxLength(), limits.SessionIdleTimeout()
+	}
 
-	if err != nil {
-		log.Println(err)
+	var containerID string
+	var runErr error
+	started := time.Now()
+	if job.Interactive {
+		containerID, runErr = w.RunSession(ctx, config)
+	} else {
+		containerID, runErr = w.GenerateAndStartContainer(ctx, config)
+	}
+
+	// Record the outcome even if the job was stopped, within a deadline of its own
+	cleanupCtx, cancelCleanup := context.WithTimeout(context.WithoutCancel(jobCtx), cleanupTimeout)
+	defer cancelCleanup()
+
+	// Count the execution time against the tenant's daily quota
+	elapsed := time.Since(started).Seconds()
+	if err := w.store.AddExecutionSeconds(cleanupCtx, job.Tenant, started, elapsed); err != nil {
+		logger.Error("Error recording usage", "error", err)
+	}
+
+	if containerID != "" {
+		logger = logger.With("container_id", containerID)
+	}
+	if runErr != nil {
+		span.SetStatus(codes.Error, runErr.Error())
+		logger.Warn("Job did not run to completion", "error", runErr)
 		// Handle the error appropriately
 	}
 
-	output := models.CompilationResult{}
+	output := models.CompilationResult{Status: models.StatusCompleted}
 
 	// Retrieve container logs
-	logs, err := w.getContainerLogs(containerID)
+	logs, err := w.getContainerLogs(cleanupCtx, containerID)
 	if err != nil {
-		log.Println(err)
+		logger.Error("Error reading container logs", "error", err)
 		output.Error = err
 		// Handle the error appropriately
 	} else {
-		output.ExitCode, err = w.getContainerExitCode(containerID)
+		output.ExitCode, err = w.getContainerExitCode(cleanupCtx, containerID)
 		if err != nil {
-			log.Println(err)
+			logger.Error("Error reading container exit code", "error", err)
 		}
 		output.Output = logs
 	}
 
-	// Set cache with a maximum duration of 15 seconds
-	err = redisClient.SetCache(job.ID, output, 15*time.Second)
-	if err != nil {
-		fmt.Println("Error setting cache:", err)
+	output = runOutcome(ctx, output, runErr, timeout)
+	metrics.ExecutionDuration.WithLabelValues(job.Language, output.Status).Observe(elapsed)
+
+	span.SetAttributes(attribute.String("job.status", output.Status), attribute.String("job.verdict", output.Verdict()))
+	logger.Info("Job finished", "status", output.Status, "verdict", output.Verdict(), "exit_code", output.ExitCode, "duration_seconds", elapsed)
+	w.saveResult(cleanupCtx, job, output)
+
+	// Keep the result for identical submissions, unless it depends on how this run went
+	if job.CacheResult && runErr == nil && (output.Verdict() == models.VerdictSuccess || output.Verdict() == models.VerdictRuntimeError) {
+		cache := appConfig.Get().Cache
+		if err := w.store.CacheResult(cleanupCtx, job.Tenant, job.Fingerprint(language), output, cache.TTL()); err != nil {
+			logger.Error("Error caching result for identical submissions", "error", err)
+		}
 	}
 
 	// Remove the Docker container
-	if err := w.StopAndRemoveContainer(containerID); err != nil {
-		log.Printf("Error stopping and removing Docker container: %v\n", err)
+	if err := w.StopAndRemoveContainer(cleanupCtx, containerID); err != nil {
+		logger.Error("Error stopping and removing container", "error", err)
 		// Handle the error appropriately
 	}
 }
 
-func (w *Worker) getContainerLogs(containerID string) (string, error) {
+// runOutcome records in the result of a job how its run ended, from the error running it returned
+// and ctx, the context it ran in. A job that did not run to the end keeps the output produced so far.
+func runOutcome(ctx context.Context, output models.CompilationResult, runErr error, timeout time.Duration) models.CompilationResult {
+	switch {
+	case errors.Is(context.Cause(ctx), errPoolStopped):
+		output.Error = errPoolStopped
+		output.ExitCode = -1
+	case errors.Is(ctx.Err(), context.Canceled):
+		output.Status = models.StatusCancelled
+		output.ExitCode = -1
+	case errors.Is(runErr, context.DeadlineExceeded):
+		// The container was still running, so its exit code is meaningless
+		output.Error = fmt.Errorf("time limit of %v exceeded", timeout)
+		output.ExitCode = -1
+	case runErr != nil:
+		// E.g. the container could not be created or started, or the session was closed
+		output.Error = runErr
+	}
+	return output
+}
+
+// sourceFiles returns the files copied into the container for a job and the entry point among them.
+// Code submitted without a file tree is stored in the language's source file, rather than passed on
+// the command line where it would be subject to argument limits and visible in process lists.
+func sourceFiles(job models.Job, language models.Language) (map[string]string, string) {
+	if len(job.Files) > 0 {
+		return job.Files, job.Entrypoint
+	}
+
+	if job.Code == "" {
+		return nil, ""
+	}
+
+	return map[string]string{language.SourceFile: job.Code}, language.SourceFile
+}
+
+// command returns the command running a job's entry point,
+// or the interpreter alone for an interactive session without code.
+func command(job models.Job, language models.Language, entrypoint string) []string {
+	if job.Interactive && entrypoint == "" {
+		return language.Repl
+	}
+
+	return language.Command(entrypoint)
+}
+
+// saveResult stores the result of a job and notifies the clients waiting for it.
+// ctx carries the job's logger and must not be cancelled with the job.
+func (w *Worker) saveResult(ctx context.Context, job models.Job, output models.CompilationResult) {
+	logger := logging.FromContext(ctx)
+
+	// Cache the result for the clients waiting for it
+	err := w.store.SaveResult(ctx, job.Key(), output, database.GetConfig().Retention.CacheTTL())
+	if err != nil {
+		logger.Error("Error caching result", "error", err)
+	}
+
+	// Keep the result after it left the cache
+	if err := w.db.SaveResult(ctx, job, output); err != nil {
+		logger.Error("Error persisting result", "error", err)
+	}
+
+	if job.BatchID != "" {
+		if err := w.store.SaveBatchResult(ctx, models.JobKey(job.Tenant, job.BatchID), job.ID, output); err != nil {
+			logger.Error("Error saving batch result", "batch_id", job.BatchID, "error", err)
+		}
+	}
+
+	if job.CallbackURL != "" {
+		if err := webhook.Enqueue(ctx, w.store, job, output); err != nil {
+			logger.Error("Error scheduling webhook", "error", err)
+		}
+	}
+}
+
+func (w *Worker) getContainerLogs(ctx context.Context, containerID string) (string, error) {
 	var logsBuffer bytes.Buffer
-	out, err := w.client.ContainerLogs(w.ctx, containerID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	out, err := w.client.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
 	if err != nil {
 		return "", err
 	}
@@ -127,9 +309,9 @@ func (w *Worker) getContainerLogs(containerID string) (string, error) {
 	return logsBuffer.String(), nil
 }
 
-func (w *Worker) getContainerExitCode(containerID string) (int, error) {
+func (w *Worker) getContainerExitCode(ctx context.Context, containerID string) (int, error) {
 	// Get container inspect information
-	containerInspect, err := w.client.ContainerInspect(context.Background(), containerID)
+	containerInspect, err := w.client.ContainerInspect(ctx, containerID)
 	if err != nil {
 		// panic(err)
 		return -1, err
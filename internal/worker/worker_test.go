@@ -0,0 +1,130 @@
+package worker
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakeDocker serves the Docker API calls of a run whose container never exits.
+// An attached container echoes its input, as a TTY does.
+func newFakeDocker(t *testing.T) *client.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/create"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Id": "looping"}`))
+		case strings.HasSuffix(r.URL.Path, "/attach"):
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			rw.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			rw.Flush()
+			io.Copy(conn, rw)
+		case strings.HasSuffix(r.URL.Path, "/wait"):
+			// The program loops forever, the headers are sent at once as Docker does
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	dockerClient, err := client.NewClientWithOpts(client.WithHost("tcp://" + server.Listener.Addr().String()))
+	if !assert.NoError(t, err, "Error creating Docker client") {
+		t.FailNow()
+	}
+	return dockerClient
+}
+
+func TestRunOutcomeTimeLimit(t *testing.T) {
+	w := &Worker{client: newFakeDocker(t)}
+	config := models.DockerConfig{ID: "1", Key: "t:1", Image: "python:3.12", Timeout: 50 * time.Millisecond, Cmd: []string{"python", "main.py"}}
+
+	_, runErr := w.GenerateAndStartContainer(context.Background(), config)
+	assert.ErrorIs(t, runErr, context.DeadlineExceeded, "The run should stop at the time limit")
+
+	// A job stopped at its time limit is not a success, whatever its container reports
+	output := runOutcome(context.Background(), models.CompilationResult{Status: models.StatusCompleted}, runErr, config.Timeout)
+	assert.Equal(t, models.VerdictSystemError, output.Verdict(), "A timed out job should be a system error")
+	assert.EqualError(t, output.Error, "time limit of 50ms exceeded", "The result should name the time limit")
+	assert.Equal(t, -1, output.ExitCode, "A timed out job should have no exit code")
+}
+
+func TestRunOutcome(t *testing.T) {
+	completed := models.CompilationResult{Status: models.StatusCompleted, ExitCode: 1, Output: "partial"}
+
+	// A job that ran to the end keeps its exit code
+	assert.Equal(t, completed, runOutcome(context.Background(), completed, nil, time.Second), "A finished job should be unchanged")
+
+	// Failing to start the container is reported
+	startErr := errors.New("no such image")
+	output := runOutcome(context.Background(), completed, startErr, time.Second)
+	assert.ErrorIs(t, output.Error, startErr, "The run error should be recorded")
+	assert.Equal(t, models.VerdictSystemError, output.Verdict(), "A job that did not start should be a system error")
+
+	// A cancelled job keeps its output
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	output = runOutcome(ctx, completed, context.Canceled, time.Second)
+	assert.Equal(t, models.VerdictCancelled, output.Verdict(), "A cancelled job should be cancelled")
+	assert.Equal(t, "partial", output.Output, "A cancelled job should keep its output")
+
+	// A job stopped by the server shutting down was not cancelled by its user
+	ctx, cancelCause := context.WithCancelCause(context.Background())
+	cancelCause(errPoolStopped)
+	output = runOutcome(ctx, completed, context.Canceled, time.Second)
+	assert.Equal(t, models.VerdictSystemError, output.Verdict(), "A job stopped on shutdown should be a system error")
+	assert.ErrorIs(t, output.Error, errPoolStopped, "The result should tell the job was stopped on shutdown")
+}
+
+func TestSourceFilesAndCommand(t *testing.T) {
+	languages := models.Languages
+	javaRun := "javac -d /tmp/classes $(find . -name '*.java') && java -cp /tmp/classes "
+
+	tests := []struct {
+		name       string
+		job        models.Job
+		files      map[string]string
+		entrypoint string
+		cmd        []string
+	}{
+		{"cpp", models.Job{Language: "cpp", Code: "int main() {}"}, map[string]string{"main.cpp": "int main() {}"}, "main.cpp", languages["cpp"].Run},
+		{"python", models.Job{Language: "python", Code: "print(1)"}, map[string]string{"main.py": "print(1)"}, "main.py", []string{"python", "main.py"}},
+		{"java", models.Job{Language: "java", Code: "class Main {}"}, map[string]string{"Main.java": "class Main {}"}, "Main.java", []string{"sh", "-c", javaRun + "Main"}},
+		{"node", models.Job{Language: "node", Code: "1"}, map[string]string{"main.js": "1"}, "main.js", []string{"node", "main.js"}},
+		{"golang", models.Job{Language: "golang", Code: "package main"}, map[string]string{"main.go": "package main"}, "main.go",
+			[]string{"sh", "-c", "if [ -f go.mod ]; then go run ./$(dirname main.go); else go run main.go; fi"}},
+		{"python project",
+			models.Job{Language: "python", Files: map[string]string{"app/main.py": "import util", "app/util.py": ""}, Entrypoint: "app/main.py"},
+			map[string]string{"app/main.py": "import util", "app/util.py": ""}, "app/main.py", []string{"python", "app/main.py"}},
+		{"java project",
+			models.Job{Language: "java", Files: map[string]string{"com/example/App.java": "package com.example;"}, Entrypoint: "com/example/App.java"},
+			map[string]string{"com/example/App.java": "package com.example;"}, "com/example/App.java", []string{"sh", "-c", javaRun + "com.example.App"}},
+		{"session without code", models.Job{Language: "python", Interactive: true}, nil, "", []string{"python"}},
+		{"session with code", models.Job{Language: "node", Code: "1", Interactive: true}, map[string]string{"main.js": "1"}, "main.js", []string{"node", "main.js"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			language := languages[tt.job.Language]
+			files, entrypoint := sourceFiles(tt.job, language)
+			assert.Equal(t, tt.files, files, "The code should land in the language's source file")
+			assert.Equal(t, tt.entrypoint, entrypoint)
+			assert.Equal(t, tt.cmd, command(tt.job, language, entrypoint))
+		})
+	}
+}
@@ -2,99 +2,303 @@ package worker
 
 import (
 	"CodeXecutor/models"
+	"CodeXecutor/pkg/database"
+	"CodeXecutor/pkg/metrics"
 	redisClient "CodeXecutor/pkg/redis"
+	"CodeXecutor/pkg/tracing"
+	"CodeXecutor/pkg/webhook"
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WorkerPool represents a dynamic pool of workers.
 type WorkerPool struct {
+	mu         sync.Mutex // guards the bounds and the workers
 	minWorkers int
 	maxWorkers int
 	jobQueue   chan models.Job
 	workers    []*Worker
+	nextID     int // ID of the next worker started
+	limiter    *Limiter
+	running    *jobRegistry
+	store      *redisClient.Store
+	db         database.Store
 	wg         sync.WaitGroup
-	ctx        context.Context
+	background sync.WaitGroup  // the cancellation listener and the webhook dispatcher
+	pulled     chan struct{}   // closed once jobs are no longer taken off the Redis queue
+	size       atomic.Int64    // number of workers, read by the readiness check
+	pulling    atomic.Bool     // whether jobs are taken off the Redis queue
+	ctx        context.Context // cancelled to stop taking jobs off the queue
 	cancel     context.CancelFunc
+	jobsCtx    context.Context // cancelled with errPoolStopped to stop the running jobs
+	cancelJobs context.CancelCauseFunc
 	// Add other worker pool-related fields and dependencies here
 }
 
-// NewWorkerPool initializes and returns a new WorkerPool instance.
-func NewWorkerPool(ctx context.Context, minWorkers, maxWorkers int) *WorkerPool {
+// NewWorkerPool initializes and returns a new WorkerPool instance taking jobs off the queue in store
+// and recording their history in db.
+func NewWorkerPool(ctx context.Context, store *redisClient.Store, db database.Store, minWorkers, maxWorkers int, limits Limits) *WorkerPool {
 	jobQueue := make(chan models.Job)
-	ctx, cancel := context.WithCancel(ctx)
+	jobsCtx, cancelJobs := context.WithCancelCause(ctx)
+	ctx, cancel := context.WithCancel(jobsCtx)
 
 	wp := &WorkerPool{
 		minWorkers: minWorkers,
 		maxWorkers: maxWorkers,
 		jobQueue:   jobQueue,
+		limiter:    NewLimiter(limits),
+		running:    newJobRegistry(),
+		store:      store,
+		db:         db,
+		pulled:     make(chan struct{}),
 		ctx:        ctx,
 		cancel:     cancel,
+		jobsCtx:    jobsCtx,
+		cancelJobs: cancelJobs,
 		// Initialize other fields and dependencies
 	}
 
+	metrics.MaxWorkers.Set(float64(maxWorkers))
+	wp.registerChecks()
+
 	wp.initWorkers()
 	// Initialize the data pulling loop
 	go PullData(wp, "code-submissions")
+	wp.background.Add(2)
+	go func() {
+		defer wp.background.Done()
+		ListenCancellations(wp)
+	}()
+	go func() {
+		defer wp.background.Done()
+		webhook.RunDispatcher(jobsCtx, store)
+	}()
 
 	return wp
 }
 
 // initWorkers starts the minimum number of workers.
 func (wp *WorkerPool) initWorkers() {
-	wp.startWorkers(wp.minWorkers)
+	wp.AddWorkers(wp.minWorkers)
 }
 
 // AddWorkers adds new workers to the pool.
 func (wp *WorkerPool) AddWorkers(count int) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
 	wp.startWorkers(count)
 }
 
-// startWorkers starts the specified number of workers.
+// Reconfigure applies new worker bounds and execution limits without interrupting running jobs.
+// Workers are started to reach the new minimum, and stopped to fit the new maximum once they finish their job.
+func (wp *WorkerPool) Reconfigure(minWorkers, maxWorkers int, limits Limits) {
+	wp.limiter.SetLimits(limits)
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	wp.minWorkers, wp.maxWorkers = minWorkers, maxWorkers
+	metrics.MaxWorkers.Set(float64(maxWorkers))
+
+	if n := len(wp.workers); n < minWorkers {
+		wp.startWorkers(minWorkers - n)
+	} else if n > maxWorkers {
+		wp.stopWorkers(n - maxWorkers)
+	}
+}
+
+// startWorkers starts the specified number of workers. The caller must hold wp.mu.
 func (wp *WorkerPool) startWorkers(count int) {
 	workersToAdd := min(count, wp.maxWorkers-len(wp.workers))
 
 	for i := 0; i < workersToAdd; i++ {
-		w := NewWorker(wp.jobQueue)
+		wp.nextID++
+		w := NewWorker(wp.jobsCtx, wp.nextID, wp.jobQueue, wp.limiter, wp.running, wp.store, wp.db)
 		wp.workers = append(wp.workers, w)
 		wp.wg.Add(1)
 		go w.Start(&wp.wg)
 	}
+	wp.size.Store(int64(len(wp.workers)))
+	metrics.Workers.Set(float64(len(wp.workers)))
 }
 
-// SubmitJob submits a job to the worker pool.
-func (wp *WorkerPool) SubmitJob(job models.Job) {
-	wp.jobQueue <- job
-}
-
+// PullData takes jobs off the Redis queue and hands them to the workers until the pool stops.
+// A job is only taken once its language and the memory budget have capacity, and the next one once
+// a worker took it, so jobs that cannot run yet stay in Redis: other processes can take them and
+// the queue metric counts them. Jobs over a language's limit never block jobs of other languages.
 func PullData(wp *WorkerPool, queueName string) {
-	client := redisClient.ConnectRedis()
-	if client == nil {
-		log.Println("Error connecting to Redis")
-		return
+	defer close(wp.pulled)
+	metrics.RegisterQueue(queueName, func() (int64, error) { return wp.store.QueueLength(wp.ctx, queueName) })
+	wp.pulling.Store(true)
+	defer wp.pulling.Store(false)
+
+	// Consecutive Redis errors, each waited out longer than the one before
+	failures := 0
+	retry := func(message string, err error) bool {
+		failures++
+		wait := retryWait(failures)
+		slog.Error(message, "queue", queueName, "error", err, "retry_in", wait)
+		select {
+		case <-time.After(wait):
+			return true
+		case <-wp.ctx.Done():
+			return false
+		}
 	}
-	defer client.Close()
-	for {
-		// Dequeue item from Redis queue
-		job, err := redisClient.DequeueItem(queueName)
+
+	for turn := 0; wp.ctx.Err() == nil; turn++ {
+		released := wp.limiter.Released()
+		languages, err := wp.store.QueueLanguages(wp.ctx, queueName)
+		if wp.ctx.Err() != nil {
+			// The pool is stopping
+			return
+		}
 		if err != nil {
-			log.Println("Error dequeueing item from Redis:", err)
+			if !retry("Error listing queued languages", err) {
+				return
+			}
+			continue
+		}
+
+		available := wp.limiter.Available(languages)
+		if len(available) == 0 {
+			// Wait for capacity, checking now and then for jobs of other languages
+			select {
+			case <-released:
+			case <-time.After(idleWait):
+			case <-wp.ctx.Done():
+			}
 			continue
 		}
 
-		// Submit the job to the worker pool
-		wp.SubmitJob(job)
+		// Take the languages in turn, so a busy one cannot starve the others
+		first := turn % len(available)
+		available = append(available[first:], available[:first]...)
+
+		job, found, err := wp.store.DequeueLanguages(wp.ctx, queueName, available)
+		if wp.ctx.Err() != nil && !found {
+			return
+		}
+		if err != nil {
+			if !retry("Error dequeueing job", err) {
+				return
+			}
+			continue
+		}
+		failures = 0
+		if found {
+			wp.dispatch(queueName, job)
+		}
 	}
 }
 
-// Stop stops the worker pool and all workers.
+const (
+	// idleWait is how long PullData waits for capacity before checking for jobs of other languages.
+	idleWait = time.Second
+	// maxRetryWait bounds how long PullData waits before retrying Redis after an error.
+	maxRetryWait = 30 * time.Second
+)
+
+// retryWait returns how long PullData waits after failures consecutive Redis errors:
+// idleWait after the first, doubled after every further one up to maxRetryWait.
+func retryWait(failures int) time.Duration {
+	return min(idleWait<<min(failures-1, 5), maxRetryWait)
+}
+
+// dispatch hands a job taken off the queue to the next free worker.
+// The dequeue span of the job starts when it left the queue and ends once a worker takes it.
+// A job that cannot be handed over, because a reconfiguration took its capacity or the pool stops,
+// is put back at the head of the queue.
+func (wp *WorkerPool) dispatch(queueName string, job models.Job) {
+	job.DequeuedAt = time.Now()
+	slog.Debug("Job dequeued", "job_id", job.ID, "tenant", job.Tenant, "request_id", job.RequestID)
+
+	// The job keeps the settings of its language while it runs, even if they are reconfigured
+	if language, ok := models.LookupLanguage(job.Language); ok {
+		job.Settings = &language
+	}
+
+	_, span := tracing.Start(tracing.Extract(wp.ctx, job), "dequeue",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithTimestamp(job.DequeuedAt),
+		trace.WithAttributes(semconv.MessagingSystemKey.String("redis"), semconv.MessagingOperationReceive, attribute.String("job.id", job.ID)),
+	)
+
+	if !wp.limiter.TryAcquire(job) {
+		span.AddEvent("requeued")
+		span.End()
+		wp.requeue(queueName, job)
+		return
+	}
+	span.AddEvent("admitted")
+
+	if wp.ctx.Err() != nil {
+		wp.limiter.Release(job)
+		tracing.End(span, wp.ctx.Err())
+		wp.requeue(queueName, job)
+		return
+	}
+
+	select {
+	case wp.jobQueue <- job:
+		// The worker releases the capacity once the job is handled
+		span.End()
+	case <-wp.ctx.Done():
+		wp.limiter.Release(job)
+		tracing.End(span, wp.ctx.Err())
+		wp.requeue(queueName, job)
+	}
+}
+
+// requeue puts a job that was not run back at the head of the queue, even if the pool is stopping.
+func (wp *WorkerPool) requeue(queueName string, job models.Job) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(wp.ctx), cleanupTimeout)
+	defer cancel()
+
+	if err := wp.store.RequeueItem(ctx, queueName, job); err != nil {
+		slog.Error("Error putting job back on the queue", "job_id", job.ID, "request_id", job.RequestID, "error", err)
+	}
+}
+
+// stopTimeout bounds how long Stop waits for running jobs to finish before stopping them.
+const stopTimeout = 15 * time.Second
+
+// errPoolStopped is the cause of the jobs stopped because the pool stopped before they finished.
+var errPoolStopped = errors.New("stopped before finishing because the server shut down")
+
+// Stop stops the worker pool and all workers. It stops taking jobs off the queue, putting back a job
+// taken but not handed to a worker, and waits for the running jobs to finish up to stopTimeout.
+// Jobs still running then are stopped and recorded as system errors.
 func (wp *WorkerPool) Stop() {
-	close(wp.jobQueue)
-	wp.wg.Wait()
 	wp.cancel()
-	log.Println("Worker pool stopped")
+	// No job is handed to the workers once the pool stopped pulling
+	<-wp.pulled
+	close(wp.jobQueue)
+
+	finished := make(chan struct{})
+	go func() {
+		wp.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(stopTimeout):
+		slog.Warn("Stopping the jobs still running", "timeout", stopTimeout)
+		wp.cancelJobs(errPoolStopped)
+		<-finished
+	}
+
+	wp.cancelJobs(errPoolStopped)
+	wp.background.Wait()
+	slog.Info("Worker pool stopped")
 }
 
 // TODO
@@ -111,13 +315,17 @@ func (wp *WorkerPool) MonitorSystemLoad() {
 			// Implement system load monitoring logic here
 			// You can access metrics like CPU usage or queue length to make decisions
 
+			wp.mu.Lock()
+			workers, minWorkers, maxWorkers := len(wp.workers), wp.minWorkers, wp.maxWorkers
+			wp.mu.Unlock()
+
 			// Example: Check if the queue is too long, and add more workers if needed
-			if len(wp.jobQueue) > 10 && len(wp.workers) < wp.maxWorkers {
+			if len(wp.jobQueue) > 10 && workers < maxWorkers {
 				wp.AddWorkers(1)
 			}
 
 			// Example: Check if the queue is empty, and remove workers if there are more than the minimum
-			if len(wp.jobQueue) == 0 && len(wp.workers) > wp.minWorkers {
+			if len(wp.jobQueue) == 0 && workers > minWorkers {
 				wp.RemoveWorkers(1)
 			}
 
@@ -129,11 +337,20 @@ func (wp *WorkerPool) MonitorSystemLoad() {
 
 // RemoveWorkers removes workers from the pool.
 func (wp *WorkerPool) RemoveWorkers(count int) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.stopWorkers(count)
+}
+
+// stopWorkers stops the specified number of workers once they finish their job. The caller must hold wp.mu.
+func (wp *WorkerPool) stopWorkers(count int) {
 	for i := 0; i < count; i++ {
 		if len(wp.workers) > 0 {
-			// w := wp.workers[len(wp.workers)-1]
+			w := wp.workers[len(wp.workers)-1]
 			wp.workers = wp.workers[:len(wp.workers)-1]
-			// w.Stop(&wp.wg) // Implement a Stop method in Worker to gracefully stop them
+			w.Stop()
 		}
 	}
+	wp.size.Store(int64(len(wp.workers)))
+	metrics.Workers.Set(float64(len(wp.workers)))
 }
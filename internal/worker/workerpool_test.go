@@ -0,0 +1,192 @@
+package worker
+
+import (
+	"CodeXecutor/models"
+	"CodeXecutor/pkg/config"
+	"CodeXecutor/pkg/database"
+	redisClient "CodeXecutor/pkg/redis"
+	"CodeXecutor/utils"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestStore connects to the Redis of the tests.
+func newTestStore(t *testing.T) *redisClient.Store {
+	// Without idle connections dialled in the background, which race with adding the hooks in go-redis v9.3.0
+	redisConfig := config.Default().Redis
+	redisConfig.MinIdleConns = 0
+	store, err := redisClient.NewStore(context.Background(), redisConfig)
+	if !assert.NoError(t, err, "Error connecting to Redis") {
+		t.FailNow()
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// newTestPool returns a pool without workers taking jobs off a queue of its own,
+// whose jobs the test receives from wp.jobQueue.
+func newTestPool(t *testing.T, queueName string, limits Limits) *WorkerPool {
+	store := newTestStore(t)
+
+	db, err := database.Open(database.DatabaseConfig{Driver: "sqlite", DSN: filepath.Join(t.TempDir(), "test.db")})
+	if !assert.NoError(t, err, "Error opening database") {
+		t.FailNow()
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// Start from an empty queue
+	for {
+		_, found, _ := store.DequeueLanguages(context.Background(), queueName, []string{"java", "python"})
+		if !found {
+			break
+		}
+	}
+
+	jobsCtx, cancelJobs := context.WithCancelCause(context.Background())
+	ctx, cancel := context.WithCancel(jobsCtx)
+	wp := &WorkerPool{
+		jobQueue:   make(chan models.Job),
+		limiter:    NewLimiter(limits),
+		running:    newJobRegistry(),
+		store:      store,
+		db:         db,
+		pulled:     make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+		jobsCtx:    jobsCtx,
+		cancelJobs: cancelJobs,
+	}
+	go PullData(wp, queueName)
+	t.Cleanup(wp.Stop)
+	return wp
+}
+
+// receive returns the next job handed to the workers of the pool.
+func receive(t *testing.T, wp *WorkerPool) models.Job {
+	select {
+	case job := <-wp.jobQueue:
+		return job
+	case <-time.After(5 * time.Second):
+		t.Fatal("No job was handed to the workers")
+		return models.Job{}
+	}
+}
+
+func TestPullDataBackpressure(t *testing.T) {
+	queueName := "test-backpressure-queue"
+	wp := newTestPool(t, queueName, Limits{MaxConcurrent: map[string]int{"java": 1}})
+	ctx := context.Background()
+
+	java1 := models.Job{ID: "java-1", Language: "java", Tenant: "test"}
+	java2 := models.Job{ID: "java-2", Language: "java", Tenant: "test"}
+	python := models.Job{ID: "python-1", Language: "python", Tenant: "test"}
+	for _, job := range []models.Job{java1, java2, python} {
+		assert.NoError(t, wp.store.EnqueueItem(ctx, queueName, job), "Error enqueuing job")
+	}
+
+	// The second java job waits in Redis for the first, without holding up the python job
+	first, second := receive(t, wp), receive(t, wp)
+	assert.ElementsMatch(t, []string{"java-1", "python-1"}, []string{first.ID, second.ID}, "The first java job and the python job should run")
+	length, err := wp.store.QueueLength(ctx, queueName)
+	assert.NoError(t, err, "Error getting queue length")
+	assert.EqualValues(t, 1, length, "The second java job should still be queued")
+
+	// Finishing the first java job lets the second one be taken
+	wp.limiter.Release(java1)
+	assert.Equal(t, "java-2", receive(t, wp).ID, "The second java job should run once the first finished")
+}
+
+func TestReconfigure(t *testing.T) {
+	queueName := "test-reconfigure-queue"
+	wp := newTestPool(t, queueName, Limits{MaxConcurrent: map[string]int{"java": 1}})
+	ctx := context.Background()
+
+	for _, id := range []string{"java-1", "java-2"} {
+		assert.NoError(t, wp.store.EnqueueItem(ctx, queueName, models.Job{ID: id, Language: "java", Tenant: "test"}), "Error enqueuing job")
+	}
+	assert.Equal(t, "java-1", receive(t, wp).ID, "The first java job should run")
+
+	// Raising the limit lets the queued java job run
+	wp.Reconfigure(0, 0, Limits{MaxConcurrent: map[string]int{"java": 2}})
+	assert.Equal(t, "java-2", receive(t, wp).ID, "The second java job should run under the new limit")
+
+	// A reload removes python while one of its jobs is queued
+	languages := map[string]models.Language{}
+	for name, language := range models.SupportedLanguages() {
+		if name != "python" {
+			languages[name] = language
+		}
+	}
+	models.SetLanguages(languages)
+	defer models.SetLanguages(models.Languages)
+	removed := models.Job{ID: utils.GenerateUniqueID(), Language: "python", Tenant: "test"}
+	wp.store.SetJobStatus(ctx, removed.Key(), models.StatusQueued)
+	assert.NoError(t, wp.store.EnqueueItem(ctx, queueName, removed), "Error enqueuing job")
+
+	// Raising the minimum starts a worker, which finishes the job rather than leaving it queued
+	wp.Reconfigure(1, 1, Limits{MaxConcurrent: map[string]int{"java": 2}})
+	assert.EqualValues(t, 1, wp.size.Load(), "A worker should be started for the new minimum")
+
+	result, err := wp.store.WaitForResult(ctx, removed.Key(), 5*time.Second)
+	assert.NoError(t, err, "The job of the removed language should get a result")
+	assert.Equal(t, models.VerdictSystemError, result.Verdict(), "The job of the removed language should be a system error")
+	assert.EqualError(t, result.Error, `language "python" is no longer supported`, "The result should tell why the job did not run")
+	status, _ := wp.store.GetJobStatus(ctx, removed.Key())
+	assert.Equal(t, models.StatusCompleted, status, "The job should no longer be queued")
+}
+
+func TestRetryWait(t *testing.T) {
+	assert.Equal(t, time.Second, retryWait(1), "The first retry should wait idleWait")
+	assert.Equal(t, 4*time.Second, retryWait(3), "Every further retry should wait twice as long")
+	assert.Equal(t, maxRetryWait, retryWait(100), "Retries should wait at most maxRetryWait")
+}
+
+func TestPullDataStopsWhileRetrying(t *testing.T) {
+	redisConfig := config.Default().Redis
+	redisConfig.MinIdleConns = 0
+	store, err := redisClient.NewStore(context.Background(), redisConfig)
+	if !assert.NoError(t, err, "Error connecting to Redis") {
+		t.FailNow()
+	}
+	// Every command of a closed store fails, as while Redis is down
+	store.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wp := &WorkerPool{limiter: NewLimiter(Limits{}), store: store, pulled: make(chan struct{}), ctx: ctx, cancel: cancel}
+	go PullData(wp, "test-retry-queue")
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case <-wp.pulled:
+	case <-time.After(time.Second):
+		t.Fatal("PullData should stop while waiting to retry")
+	}
+}
+
+func TestPullDataRequeuesOnStop(t *testing.T) {
+	queueName := "test-requeue-queue"
+	wp := newTestPool(t, queueName, Limits{})
+	ctx := context.Background()
+
+	job := models.Job{ID: "waiting", Language: "python", Tenant: "test"}
+	assert.NoError(t, wp.store.EnqueueItem(ctx, queueName, job), "Error enqueuing job")
+
+	// Without a free worker the job is taken off the queue and held
+	assert.Eventually(t, func() bool {
+		length, err := wp.store.QueueLength(ctx, queueName)
+		return err == nil && length == 0
+	}, 5*time.Second, 10*time.Millisecond, "The job should be taken off the queue")
+
+	// Stopping puts it back rather than dropping it
+	wp.cancel()
+	<-wp.pulled
+	requeued, err := wp.store.DequeueItem(ctx, queueName)
+	assert.NoError(t, err, "Error dequeuing job")
+	assert.Equal(t, job, requeued, "The job should be back in the queue")
+	assert.Zero(t, wp.limiter.running["python"], "The job should release its capacity")
+}
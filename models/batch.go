@@ -0,0 +1,14 @@
+package models
+
+// Batch groups jobs submitted together.
+type Batch struct {
+	ID     string   `json:"id"`     // unique identifier
+	JobIDs []string `json:"jobids"` // Jobs of the batch, in submission order
+	Time   int      `json:"time"`   // The time of submission
+	Tenant string   `json:"tenant"` // Tenant owning the batch
+}
+
+// Key returns the key identifying the batch in storage, scoped like the keys of jobs.
+func (batch Batch) Key() string {
+	return JobKey(batch.Tenant, batch.ID)
+}
@@ -0,0 +1,25 @@
+package models
+
+// Delivery is a pending webhook notification of a finished job.
+type Delivery struct {
+	JobID       string            `json:"jobid"`       // Job whose result is delivered
+	Tenant      string            `json:"tenant"`      // Tenant owning the job
+	CallbackURL string            `json:"callbackurl"` // URL the result is posted to
+	Result      CompilationResult `json:"result"`      // Final result of the job
+	Attempt     int               `json:"attempt"`     // Number of the next delivery attempt, starting at 1
+	RequestID   string            `json:"request_id"`  // Request that submitted the job, for the logs
+}
+
+// DeliveryAttempt records the outcome of one attempt to deliver a webhook.
+type DeliveryAttempt struct {
+	Attempt    int    `json:"attempt"`
+	Time       int64  `json:"time"`                 // Unix time of the attempt
+	StatusCode int    `json:"statuscode,omitempty"` // HTTP status returned by the callback, if any
+	Error      string `json:"error,omitempty"`      // Why the attempt failed
+	Delivered  bool   `json:"delivered"`
+}
+
+// JobKey returns the storage key of the delivered job.
+func (delivery Delivery) JobKey() string {
+	return JobKey(delivery.Tenant, delivery.JobID)
+}
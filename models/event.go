@@ -0,0 +1,16 @@
+package models
+
+// Types of the events recorded while a job runs.
+const (
+	EventStatus = "status" // the job status changed, Data holds the new status
+	EventStdout = "stdout" // Data holds a chunk written to stdout
+	EventStderr = "stderr" // Data holds a chunk written to stderr
+	EventResult = "result" // the job finished, Data holds the JSON encoded CompilationResult
+)
+
+// Event is a status change, output chunk or final result of a job.
+type Event struct {
+	ID   string // Position of the event in the job's event stream
+	Type string // One of the Event* types
+	Data string // Payload of the event
+}
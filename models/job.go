@@ -1,16 +1,98 @@
 package models
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
 type Job struct {
-	ID       string `json:"id"`       // unique identifier
-	Language string `json:"language"` // Programming language used in the code
-	Code     string `json:"code"`     // The user's code
-	Time     int    `json:"time"`     // The time of submission
+	ID           string            `json:"id"`                      // unique identifier
+	Language     string            `json:"language"`                // Programming language used in the code
+	Code         string            `json:"code"`                    // The user's code
+	Files        map[string]string `json:"files,omitempty"`         // File tree of a project, path to content, used instead of Code
+	Entrypoint   string            `json:"entrypoint,omitempty"`    // Path of the file in Files that is run
+	Time         int               `json:"time"`                    // The time of submission
+	Interactive  bool              `json:"interactive,omitempty"`   // Whether stdin is relayed from a live session
+	CallbackURL  string            `json:"callback_url,omitempty"`  // URL notified with the result once the job finishes
+	BatchID      string            `json:"batch_id,omitempty"`      // Batch the job was submitted in, if any
+	Tenant       string            `json:"tenant,omitempty"`        // Tenant owning the job, from the API key it was submitted with
+	Stdin        string            `json:"stdin,omitempty"`         // Input written to the program's stdin
+	TimeLimit    float64           `json:"time_limit,omitempty"`    // Run time limit in seconds, at most the language's
+	MemoryLimit  int64             `json:"memory_limit,omitempty"`  // Memory limit in bytes, at most the language's
+	RequestID    string            `json:"request_id,omitempty"`    // Request that submitted the job, to correlate its logs
+	TraceContext map[string]string `json:"trace_context,omitempty"` // W3C trace context of the span that queued the job
+	CacheResult  bool              `json:"cache_result,omitempty"`  // Whether the result is cached for identical submissions
+	DequeuedAt   time.Time         `json:"-"`                       // When a worker pool took the job off the queue
+	Settings     *Language         `json:"-"`                       // Settings of the language when the job was admitted, kept while it runs
+}
+
+// LanguageSettings returns the settings of the job's language: those it was admitted with,
+// or the current ones before it is admitted.
+func (job Job) LanguageSettings() (Language, bool) {
+	if job.Settings != nil {
+		return *job.Settings, true
+	}
+	return LookupLanguage(job.Language)
+}
+
+// Limits returns the run time and memory limits of a job, those of its language unless the job asks for less.
+func (job Job) Limits(language Language) (time.Duration, int64) {
+	timeout, memory := language.Timeout, language.Memory
+	if job.TimeLimit > 0 {
+		timeout = min(timeout, time.Duration(job.TimeLimit*float64(time.Second)))
+	}
+	if job.MemoryLimit > 0 {
+		memory = min(memory, job.MemoryLimit)
+	}
+	return timeout, memory
+}
+
+// Fingerprint identifies what running the job with the language's settings produces: the image and
+// command, the code, the stdin and the limits. Jobs with the same fingerprint give the same result
+// unless the program itself is not deterministic.
+func (job Job) Fingerprint(language Language) string {
+	timeout, memory := job.Limits(language)
+	// Marshalling sorts the file paths, so equal jobs always encode the same
+	data, _ := json.Marshal(struct {
+		Image      string
+		Run        []string
+		SourceFile string
+		Code       string
+		Files      map[string]string
+		Entrypoint string
+		Stdin      string
+		Timeout    time.Duration
+		Memory     int64
+	}{language.Image, language.Run, language.SourceFile, job.Code, job.Files, job.Entrypoint, job.Stdin, timeout, memory})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
 // DockerConfig represents the configuration for the Docker container.
 type DockerConfig struct {
-	ID       string `json:"id"`       // unique identifier
-	Image    string `json:"image"`    // Docker image name, e.g., "python:3.9"
-	Code     string `json:"code"`     // User's code to be executed
-	Language string `json:"language"` // Programming language (used for selecting the image)
+	ID       string            `json:"id"`       // unique identifier
+	Key      string            `json:"key"`      // Storage key of the job, see JobKey
+	Image    string            `json:"image"`    // Docker image name, e.g., "python:3.9"
+	Language string            `json:"language"` // Programming language (used for selecting the image)
+	Memory   int64             `json:"memory"`   // Memory limit of the container in bytes
+	Timeout  time.Duration     `json:"timeout"`  // Run time limit of the container, the maximum length of an interactive session
+	Idle     time.Duration     `json:"idle"`     // Time an interactive session may go without input or output
+	Stdin    string            `json:"stdin"`    // Input written to the program's stdin
+	Cmd      []string          `json:"cmd"`      // Command run in the container
+	Files    map[string]string `json:"files"`    // Source files copied into the working directory before the command runs
+}
+
+// JobKey returns the key identifying a job of a tenant in storage.
+// Scoping the key to the tenant keeps tenants from reading each other's jobs by guessing IDs.
+// Tenant names cannot contain ":" and job IDs are UUIDs, so a key names a single tenant and job.
+func JobKey(tenant, jobID string) string {
+	return tenant + ":" + jobID
+}
+
+// Key returns the key identifying the job in storage.
+func (job Job) Key() string {
+	return JobKey(job.Tenant, job.ID)
 }
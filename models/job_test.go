@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	language := Language{Image: "python:3.12", Memory: 64 << 20, Timeout: 5 * time.Second, Run: []string{"python", "main.py"}, SourceFile: "main.py"}
+	job := Job{ID: "a", Tenant: "t", Language: "python", Code: "print(input())", Stdin: "hi", Time: 1}
+
+	// Fields that do not change what runs do not change the fingerprint
+	same := job
+	same.ID, same.Time, same.RequestID = "b", 2, "r"
+	assert.Equal(t, job.Fingerprint(language), same.Fingerprint(language), "Identical submissions should have the same fingerprint")
+
+	// Limits above the language's are the language's, so they run the same
+	same.TimeLimit = 60
+	assert.Equal(t, job.Fingerprint(language), same.Fingerprint(language), "Limits capped by the language should not change the fingerprint")
+
+	changes := map[string]func(*Job, *Language){
+		"code":         func(j *Job, _ *Language) { j.Code = "print(1)" },
+		"stdin":        func(j *Job, _ *Language) { j.Stdin = "bye" },
+		"time limit":   func(j *Job, _ *Language) { j.TimeLimit = 1 },
+		"memory limit": func(j *Job, _ *Language) { j.MemoryLimit = 32 << 20 },
+		"files":        func(j *Job, _ *Language) { j.Files = map[string]string{"main.py": "print(1)"} },
+		"image":        func(_ *Job, l *Language) { l.Image = "python:3.13" },
+	}
+	for name, change := range changes {
+		changedJob, changedLanguage := job, language
+		change(&changedJob, &changedLanguage)
+		assert.NotEqual(t, job.Fingerprint(language), changedJob.Fingerprint(changedLanguage), "Changing the %s should change the fingerprint", name)
+	}
+}
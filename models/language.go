@@ -0,0 +1,99 @@
+package models
+
+import (
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Language describes how code written in a programming language is executed.
+type Language struct {
+	Image      string        `json:"image"`      // Docker image used to run the code
+	Memory     int64         `json:"memory"`     // Memory limit of the container in bytes
+	Timeout    time.Duration `json:"timeout"`    // Run time limit of the container
+	Repl       []string      `json:"repl"`       // Command starting an interactive interpreter, if the language has one
+	Run        []string      `json:"run"`        // Command running a project from the working directory, see Command
+	SourceFile string        `json:"sourcefile"` // Name of the file holding code submitted without a file tree
+}
+
+// Command returns the command running a project with the given entry point.
+// In Run, {entrypoint} is replaced by the path of the entry point and {class}
+// by the class it declares, e.g. "com.example.Main" for "com/example/Main.java".
+func (l Language) Command(entrypoint string) []string {
+	class := strings.ReplaceAll(strings.TrimSuffix(entrypoint, path.Ext(entrypoint)), "/", ".")
+	replacer := strings.NewReplacer("{entrypoint}", entrypoint, "{class}", class)
+
+	cmd := make([]string, len(l.Run))
+	for i, arg := range l.Run {
+		cmd[i] = replacer.Replace(arg)
+	}
+	return cmd
+}
+
+// Languages maps the built-in programming languages to their execution settings.
+// The languages supported at runtime are set from the configuration with SetLanguages.
+var Languages = map[string]Language{
+	"cpp": {
+		Image:      "gcc:10.3",
+		Memory:     256 << 20,
+		Timeout:    2 * time.Second,
+		Run:        []string{"sh", "-c", "g++ -O2 -o /tmp/main $(find . -name '*.cpp') && /tmp/main"},
+		SourceFile: "main.cpp",
+	},
+	"python": {
+		Image:      "python:3.9",
+		Memory:     128 << 20,
+		Timeout:    2 * time.Second,
+		Repl:       []string{"python"},
+		Run:        []string{"python", "{entrypoint}"},
+		SourceFile: "main.py",
+	},
+	"java": {
+		Image:      "openjdk:11.0.12",
+		Memory:     512 << 20,
+		Timeout:    2 * time.Second,
+		Repl:       []string{"jshell"},
+		Run:        []string{"sh", "-c", "javac -d /tmp/classes $(find . -name '*.java') && java -cp /tmp/classes {class}"},
+		SourceFile: "Main.java",
+	},
+	"node": {
+		Image:      "node:14.17",
+		Memory:     256 << 20,
+		Timeout:    2 * time.Second,
+		Repl:       []string{"node"},
+		Run:        []string{"node", "{entrypoint}"},
+		SourceFile: "main.js",
+	},
+	"golang": {
+		Image:      "golang:1.21",
+		Memory:     512 << 20,
+		Timeout:    2 * time.Second,
+		Run:        []string{"sh", "-c", "if [ -f go.mod ]; then go run ./$(dirname {entrypoint}); else go run {entrypoint}; fi"},
+		SourceFile: "main.go",
+	},
+	// Add more languages and their corresponding images as needed
+}
+
+// supported holds the languages currently supported, swapped as a whole when the configuration changes.
+var supported atomic.Pointer[map[string]Language]
+
+func init() {
+	SetLanguages(Languages)
+}
+
+// SetLanguages replaces the supported languages. The map must not be modified afterwards.
+func SetLanguages(languages map[string]Language) {
+	supported.Store(&languages)
+}
+
+// LookupLanguage returns the settings of a supported language.
+func LookupLanguage(name string) (Language, bool) {
+	language, ok := (*supported.Load())[name]
+	return language, ok
+}
+
+// SupportedLanguages returns the supported languages. The map must not be modified.
+func SupportedLanguages() map[string]Language {
+	return *supported.Load()
+}
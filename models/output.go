@@ -1,7 +1,77 @@
 package models
 
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Job statuses recorded while a job moves through the system.
+const (
+	StatusQueued    = "queued"    // waiting in the submission queue
+	StatusRunning   = "running"   // picked up by a worker
+	StatusCompleted = "completed" // finished executing
+	StatusCancelled = "cancelled" // cancelled by the user before finishing
+)
+
+// Verdicts classify how a finished job went.
+const (
+	VerdictSuccess      = "success"       // exited with code 0
+	VerdictRuntimeError = "runtime_error" // exited with a non-zero code, e.g. a compile error or an exception
+	VerdictSystemError  = "system_error"  // could not be run to the end, e.g. the container failed to start or the session timed out
+	VerdictCancelled    = "cancelled"     // cancelled by the user
+)
+
 type CompilationResult struct {
+	Status   string // Final status of the job
 	ExitCode int    // Indicates exit code of container
 	Output   string // Compiler output or execution results
 	Error    error  // Compilation or execution errors, if any
+	Cached   bool   // Whether the result was stored for an identical submission rather than produced by running the job
+}
+
+// compilationResultJSON is the serialized form of CompilationResult with the error stored as text.
+type compilationResultJSON struct {
+	Status   string
+	ExitCode int
+	Output   string
+	Error    *string
+	Cached   bool `json:",omitempty"`
+}
+
+// MarshalJSON encodes the result, storing the error as its message.
+func (r CompilationResult) MarshalJSON() ([]byte, error) {
+	data := compilationResultJSON{Status: r.Status, ExitCode: r.ExitCode, Output: r.Output, Cached: r.Cached}
+	if r.Error != nil {
+		message := r.Error.Error()
+		data.Error = &message
+	}
+	return json.Marshal(data)
+}
+
+// UnmarshalJSON decodes a result encoded by MarshalJSON.
+func (r *CompilationResult) UnmarshalJSON(b []byte) error {
+	var data compilationResultJSON
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	*r = CompilationResult{Status: data.Status, ExitCode: data.ExitCode, Output: data.Output, Cached: data.Cached}
+	if data.Error != nil {
+		r.Error = errors.New(*data.Error)
+	}
+	return nil
+}
+
+// Verdict classifies the result of a finished job.
+func (r CompilationResult) Verdict() string {
+	switch {
+	case r.Status == StatusCancelled:
+		return VerdictCancelled
+	case r.Error != nil:
+		return VerdictSystemError
+	case r.ExitCode != 0:
+		return VerdictRuntimeError
+	default:
+		return VerdictSuccess
+	}
 }
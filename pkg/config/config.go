@@ -0,0 +1,669 @@
+package config
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// FileName is the name of the configuration file, looked up in the config directory.
+const FileName = "codexecutor.toml"
+
+// PathEnv names the environment variable holding the path of the configuration file.
+const PathEnv = "CODEXECUTOR_CONFIG"
+
+// minMemoryMB is the smallest memory limit Docker accepts for a container.
+const minMemoryMB = 6
+
+// envPrefix prefixes the environment variables overriding single settings, e.g. CODEXECUTOR_REDIS_ADDR.
+const envPrefix = "CODEXECUTOR_"
+
+type ServerConfig struct {
+	Addr string `toml:"addr"` // host:port the HTTP server listens on
+}
+
+// Deployments of Redis, set as redis.mode.
+const (
+	RedisSingle   = "single"   // a single server at addr
+	RedisSentinel = "sentinel" // a master found through the sentinels at addrs, followed on failover
+	RedisCluster  = "cluster"  // a cluster discovered from the nodes at addrs
+)
+
+type RedisConfig struct {
+	Mode                  string   `toml:"mode"`                     // single, sentinel or cluster
+	Addr                  string   `toml:"addr"`                     // host:port of the Redis server in single mode
+	Addrs                 []string `toml:"addrs"`                    // host:port of the sentinels, or of the cluster nodes
+	MasterName            string   `toml:"master_name"`              // Name of the master monitored by the sentinels
+	Username              string   `toml:"username"`                 // ACL user, the default user if empty
+	Password              string   `toml:"password"`                 // Password of the user, if it requires one
+	SentinelUsername      string   `toml:"sentinel_username"`        // ACL user of the sentinels
+	SentinelPassword      string   `toml:"sentinel_password"`        // Password of the sentinels
+	DB                    int      `toml:"db"`                       // Database number, always 0 in a cluster
+	PoolSize              int      `toml:"pool_size"`                // Maximum number of connections per node
+	MinIdleConns          int      `toml:"min_idle_conns"`           // Connections kept open while idle
+	MaxRetries            int      `toml:"max_retries"`              // Retries of a failed command, -1 disables retries
+	MinRetryBackoffMs     int      `toml:"min_retry_backoff_ms"`     // Backoff before the first retry
+	MaxRetryBackoffMs     int      `toml:"max_retry_backoff_ms"`     // Upper bound of the backoff between retries
+	TLS                   bool     `toml:"tls"`                      // Connect over TLS
+	TLSCAFile             string   `toml:"tls_ca_file"`              // PEM certificates trusted for the servers, the system's if empty
+	TLSCertFile           string   `toml:"tls_cert_file"`            // PEM client certificate, for servers requiring one
+	TLSKeyFile            string   `toml:"tls_key_file"`             // PEM key of the client certificate
+	TLSServerName         string   `toml:"tls_server_name"`          // Name verified in the server certificates, the host if empty
+	TLSInsecureSkipVerify bool     `toml:"tls_insecure_skip_verify"` // Skip verifying the server certificates, for testing only
+}
+
+type WorkersConfig struct {
+	Min int `toml:"min"` // Workers started with the pool
+	Max int `toml:"max"` // Workers the pool may grow to
+}
+
+// LanguageConfig overrides the settings of a built-in language, or defines a new one.
+// Settings left out keep the built-in value.
+type LanguageConfig struct {
+	Image          string   `toml:"image"`           // Docker image running the code
+	MemoryMB       int64    `toml:"memory_mb"`       // Memory limit of the container
+	TimeoutSeconds float64  `toml:"timeout_seconds"` // Run time limit of the container
+	Repl           []string `toml:"repl"`            // Command starting an interactive interpreter
+	Run            []string `toml:"run"`             // Command running a project, see models.Language
+	SourceFile     string   `toml:"source_file"`     // File holding code submitted without a file tree
+	MaxConcurrent  int      `toml:"max_concurrent"`  // Executions of the language at the same time, 0 for no limit
+}
+
+type LimitsConfig struct {
+	MemoryBudgetMB     int64 `toml:"memory_budget_mb"`     // Memory reserved by all running containers, 0 for no budget
+	SessionIdleSeconds int   `toml:"session_idle_seconds"` // Time without input or output after which an interactive session is closed
+	SessionMaxSeconds  int   `toml:"session_max_seconds"`  // Time after which an interactive session is closed
+}
+
+// CacheConfig configures the caching of the results of identical submissions.
+type CacheConfig struct {
+	Enabled    bool `toml:"enabled"`     // Answer identical submissions with the stored result
+	TTLSeconds int  `toml:"ttl_seconds"` // How long a result is stored
+}
+
+// TTL returns how long a cached result is stored.
+func (c CacheConfig) TTL() time.Duration {
+	return time.Duration(c.TTLSeconds) * time.Second
+}
+
+// Config is the configuration of the service.
+type Config struct {
+	Server    ServerConfig              `toml:"server"`
+	Redis     RedisConfig               `toml:"redis"`
+	Workers   WorkersConfig             `toml:"workers"`
+	Languages map[string]LanguageConfig `toml:"languages"`
+	Limits    LimitsConfig              `toml:"limits"`
+	Cache     CacheConfig               `toml:"cache"`
+
+	dir   string               // directory of the configuration file, holding the other config files
+	files map[string]Validator // configurations of the registered files, by file name
+}
+
+// Validator is a configuration able to check its settings.
+type Validator interface {
+	Validate() error
+}
+
+// registered holds the functions returning the defaults of the registered files, by file name.
+var registered = map[string]func() Validator{}
+
+// Register adds the configuration a package keeps in a file of its own next to the configuration file,
+// e.g. "database.toml". newDefault returns a pointer to a struct of TOML sections holding the defaults.
+// The file is loaded and validated with the configuration file, and its settings are overridden by the
+// environment and flags the same way, e.g. CODEXECUTOR_DATABASE_DSN or -database.dsn.
+// Packages register from an init function, so their files are loaded by Init.
+func Register(file string, newDefault func() Validator) {
+	registered[file] = newDefault
+}
+
+// Registered returns the configuration of a registered file, as loaded with the current configuration.
+// The files are read on startup only: Reload keeps them as they were.
+func Registered[T Validator](file string) T {
+	return Get().files[file].(T)
+}
+
+// watchInterval is how often Watch checks the configuration file for changes.
+const watchInterval = 2 * time.Second
+
+var (
+	current  atomic.Pointer[Config]
+	loadOnce sync.Once
+
+	// The file and flags the configuration was loaded from, to reload it the same way
+	loadedPath      string
+	loadedOverrides map[string]string
+)
+
+// Default returns the configuration used for settings not set otherwise.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{Addr: "localhost:8080"},
+		Redis: RedisConfig{
+			Mode:              RedisSingle,
+			Addr:              "localhost:6379",
+			PoolSize:          10,
+			MinIdleConns:      5,
+			MaxRetries:        3,
+			MinRetryBackoffMs: 8,
+			MaxRetryBackoffMs: 512,
+		},
+		Workers: WorkersConfig{Min: 1, Max: 4},
+		Languages: map[string]LanguageConfig{
+			"java":   {MaxConcurrent: 2},
+			"golang": {MaxConcurrent: 2},
+		},
+		Limits: LimitsConfig{MemoryBudgetMB: 2 << 10, SessionIdleSeconds: 120, SessionMaxSeconds: 600},
+		Cache:  CacheConfig{TTLSeconds: 3600},
+		dir:    "config",
+		files:  defaultFiles(),
+	}
+}
+
+// defaultFiles returns the defaults of the registered files.
+func defaultFiles() map[string]Validator {
+	files := make(map[string]Validator, len(registered))
+	for file, newDefault := range registered {
+		files[file] = newDefault()
+	}
+	return files
+}
+
+// loadFiles reads the registered files found next to the configuration file over their defaults.
+// A missing file keeps the defaults, which the environment can still override.
+func (c *Config) loadFiles() error {
+	for file, value := range c.files {
+		path := filepath.Join(c.dir, file)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		metadata, err := toml.DecodeFile(path, value)
+		if err != nil {
+			return err
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown settings %v", path, undecoded)
+		}
+	}
+	return nil
+}
+
+// Load reads the configuration: the defaults, overridden by the file at path unless it is empty,
+// then by the environment and by overrides, which map setting names such as "redis.addr" to values.
+// The result is validated.
+func Load(path string, overrides map[string]string) (*Config, error) {
+	config := Default()
+
+	if path != "" {
+		metadata, err := toml.DecodeFile(path, config)
+		if err != nil {
+			return nil, err
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("%s: unknown settings %v", path, undecoded)
+		}
+		config.dir = filepath.Dir(path)
+
+		// A language table in the file replaces the default of the language, keep the limit it leaves out
+		for name, language := range Default().Languages {
+			if configured, ok := config.Languages[name]; ok && !metadata.IsDefined("languages", name, "max_concurrent") {
+				configured.MaxConcurrent = language.MaxConcurrent
+				config.Languages[name] = configured
+			}
+		}
+	}
+	if err := config.loadFiles(); err != nil {
+		return nil, err
+	}
+
+	for _, s := range config.settings() {
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	for name, value := range overrides {
+		s, ok := config.setting(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown setting %s", name)
+		}
+		if err := s.set(value); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Init loads the configuration from the command line arguments and makes it the current one.
+// -config or $CODEXECUTOR_CONFIG names the file, and every setting can be overridden by a flag named after it, e.g. -redis.addr.
+func Init(args []string) (*Config, error) {
+	flags := flag.NewFlagSet("codexecutor", flag.ContinueOnError)
+	file := flags.String("config", "", fmt.Sprintf("path of the configuration file, defaults to $%s or config/%s", PathEnv, FileName))
+
+	flagged := map[string]string{}
+	for _, s := range Default().settings() {
+		name := s.name
+		flags.Func(name, fmt.Sprintf("overrides %s, also set by $%s", name, s.env), func(value string) error {
+			flagged[name] = value
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	resolved, err := Path(*file)
+	if err != nil {
+		return nil, err
+	}
+	config, err := Load(resolved, flagged)
+	if err != nil {
+		return nil, err
+	}
+
+	loadedPath, loadedOverrides = resolved, flagged
+	current.Store(config)
+	return config, nil
+}
+
+// Reload loads the configuration again from the file and flags given to Init and makes it the current one.
+// If it is invalid, the current configuration is kept and the error returned.
+func Reload() (*Config, error) {
+	config, err := Load(loadedPath, loadedOverrides)
+	if err != nil {
+		return nil, err
+	}
+	if previous := current.Load(); previous != nil {
+		config.files = previous.files
+	}
+
+	current.Store(config)
+	return config, nil
+}
+
+// Watch calls reload whenever the configuration file changes or the process receives SIGHUP, until ctx is done.
+func Watch(ctx context.Context, reload func()) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	modified := modTime(loadedPath)
+	for {
+		select {
+		case <-hangup:
+			modified = modTime(loadedPath)
+			reload()
+		case <-ticker.C:
+			if t := modTime(loadedPath); !t.Equal(modified) {
+				modified = t
+				reload()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// modTime returns when a file was last modified, or the zero time if there is none.
+func modTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Get returns the current configuration. Unless Init set it, it is loaded from the default file on first use.
+func Get() *Config {
+	if config := current.Load(); config != nil {
+		return config
+	}
+
+	loadOnce.Do(func() {
+		path, err := Path("")
+		if err != nil {
+			panic(err)
+		}
+		config, err := Load(path, nil)
+		if err != nil {
+			panic(err)
+		}
+		if current.CompareAndSwap(nil, config) {
+			loadedPath = path
+		}
+	})
+	return current.Load()
+}
+
+// Path returns the path of the configuration file: the given one, $CODEXECUTOR_CONFIG,
+// or config/codexecutor.toml in the working directory or the closest parent having one.
+// Without any file it returns "" and only the defaults and the environment apply.
+func Path(path string) (string, error) {
+	if path == "" {
+		path = os.Getenv(PathEnv)
+	}
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		candidate := filepath.Join(dir, "config", FileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// File returns the path of another config file, which lives next to the configuration file.
+func File(name string) string {
+	return filepath.Join(Get().dir, name)
+}
+
+// Validate checks that the settings are usable, reporting every invalid one.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(name, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", name, fmt.Sprintf(format, args...)))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		invalid("server.addr", "must be host:port: %v", err)
+	}
+
+	switch c.Redis.Mode {
+	case RedisSingle:
+		if _, _, err := net.SplitHostPort(c.Redis.Addr); err != nil {
+			invalid("redis.addr", "must be host:port: %v", err)
+		}
+	case RedisSentinel, RedisCluster:
+		if len(c.Redis.Addrs) == 0 {
+			invalid("redis.addrs", "must list at least one node in %s mode", c.Redis.Mode)
+		}
+		for _, addr := range c.Redis.Addrs {
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				invalid("redis.addrs", "must be host:port: %v", err)
+			}
+		}
+	default:
+		invalid("redis.mode", "must be %s, %s or %s", RedisSingle, RedisSentinel, RedisCluster)
+	}
+	if c.Redis.Mode == RedisSentinel && c.Redis.MasterName == "" {
+		invalid("redis.master_name", "is required in sentinel mode")
+	}
+	if c.Redis.DB < 0 || (c.Redis.Mode == RedisCluster && c.Redis.DB != 0) {
+		invalid("redis.db", "must not be negative, and must be 0 in cluster mode")
+	}
+	if (c.Redis.TLSCertFile == "") != (c.Redis.TLSKeyFile == "") {
+		invalid("redis.tls_key_file", "must be set together with tls_cert_file")
+	}
+	if c.Redis.PoolSize < 1 {
+		invalid("redis.pool_size", "must be at least 1")
+	}
+	if c.Redis.MinIdleConns < 0 || c.Redis.MinIdleConns > c.Redis.PoolSize {
+		invalid("redis.min_idle_conns", "must be between 0 and pool_size")
+	}
+	if c.Redis.MaxRetries < -1 {
+		invalid("redis.max_retries", "must be -1 or more")
+	}
+	if c.Redis.MinRetryBackoffMs < 0 || c.Redis.MaxRetryBackoffMs < c.Redis.MinRetryBackoffMs {
+		invalid("redis.max_retry_backoff_ms", "must not be less than min_retry_backoff_ms, which must not be negative")
+	}
+
+	if c.Workers.Min < 1 {
+		invalid("workers.min", "must be at least 1")
+	}
+	if c.Workers.Max < c.Workers.Min {
+		invalid("workers.max", "must not be less than workers.min")
+	}
+
+	if c.Limits.MemoryBudgetMB < 0 {
+		invalid("limits.memory_budget_mb", "must not be negative")
+	}
+	if c.Limits.SessionIdleSeconds < 1 {
+		invalid("limits.session_idle_seconds", "must be at least 1")
+	}
+	if c.Limits.SessionMaxSeconds < 1 {
+		invalid("limits.session_max_seconds", "must be at least 1")
+	}
+
+	if c.Cache.TTLSeconds < 1 {
+		invalid("cache.ttl_seconds", "must be at least 1")
+	}
+
+	for name, language := range c.LanguageTable() {
+		prefix := "languages." + name
+		if language.Image == "" {
+			invalid(prefix+".image", "is required")
+		}
+		if len(language.Run) == 0 {
+			invalid(prefix+".run", "is required")
+		}
+		if language.SourceFile == "" {
+			invalid(prefix+".source_file", "is required")
+		} else if filepath.Base(language.SourceFile) != language.SourceFile {
+			invalid(prefix+".source_file", "must be a file name")
+		}
+		if language.Memory < minMemoryMB<<20 {
+			invalid(prefix+".memory_mb", "must be at least %d", minMemoryMB)
+		}
+		if language.Timeout <= 0 {
+			invalid(prefix+".timeout_seconds", "must be positive")
+		}
+	}
+	for name, language := range c.Languages {
+		if language.MaxConcurrent < 0 {
+			invalid("languages."+name+".max_concurrent", "must not be negative")
+		}
+	}
+
+	for _, file := range c.fileNames() {
+		if err := c.files[file].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// fileNames returns the names of the registered files in order.
+func (c *Config) fileNames() []string {
+	files := make([]string, 0, len(c.files))
+	for file := range c.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// LanguageTable returns the built-in languages with the configured settings applied, and the configured new languages.
+func (c *Config) LanguageTable() map[string]models.Language {
+	languages := copyLanguages(models.Languages)
+	for name, configured := range c.Languages {
+		language := languages[name]
+		if configured.Image != "" {
+			language.Image = configured.Image
+		}
+		if configured.MemoryMB != 0 {
+			language.Memory = configured.MemoryMB << 20
+		}
+		if configured.TimeoutSeconds != 0 {
+			language.Timeout = time.Duration(configured.TimeoutSeconds * float64(time.Second))
+		}
+		if configured.Repl != nil {
+			language.Repl = configured.Repl
+		}
+		if configured.Run != nil {
+			language.Run = configured.Run
+		}
+		if configured.SourceFile != "" {
+			language.SourceFile = configured.SourceFile
+		}
+		languages[name] = language
+	}
+	return languages
+}
+
+// MaxConcurrent returns the limits on concurrent executions by language.
+func (c *Config) MaxConcurrent() map[string]int {
+	limits := map[string]int{}
+	for name, language := range c.Languages {
+		if language.MaxConcurrent > 0 {
+			limits[name] = language.MaxConcurrent
+		}
+	}
+	return limits
+}
+
+// MemoryBudget returns the memory budget of all running containers in bytes.
+func (c LimitsConfig) MemoryBudget() int64 {
+	return c.MemoryBudgetMB << 20
+}
+
+// SessionIdleTimeout returns how long an interactive session may go without input or output.
+func (c LimitsConfig) SessionIdleTimeout() time.Duration {
+	return time.Duration(c.SessionIdleSeconds) * time.Second
+}
+
+// SessionMaxLength returns how long an interactive session may run.
+func (c LimitsConfig) SessionMaxLength() time.Duration {
+	return time.Duration(c.SessionMaxSeconds) * time.Second
+}
+
+func copyLanguages(languages map[string]models.Language) map[string]models.Language {
+	copied := make(map[string]models.Language, len(languages))
+	for name, language := range languages {
+		copied[name] = language
+	}
+	return copied
+}
+
+// setting is a single value of a section that can be overridden by the environment or a flag.
+type setting struct {
+	name  string // section.key, as in the file
+	env   string
+	value reflect.Value
+}
+
+// settings returns the settings of the sections of c and of the registered files, which point into c.
+func (c *Config) settings() []setting {
+	settings := sectionSettings(reflect.ValueOf(c).Elem())
+	for _, file := range c.fileNames() {
+		settings = append(settings, sectionSettings(reflect.ValueOf(c.files[file]).Elem())...)
+	}
+	return settings
+}
+
+// sectionSettings returns the settings of the sections of a configuration struct, which point into it.
+// Tables such as the languages cannot be set from a string and are left out.
+func sectionSettings(config reflect.Value) []setting {
+	var settings []setting
+
+	for i := 0; i < config.NumField(); i++ {
+		section := config.Field(i)
+		sectionName := config.Type().Field(i).Tag.Get("toml")
+		if section.Kind() != reflect.Struct || sectionName == "" {
+			continue
+		}
+
+		for j := 0; j < section.NumField(); j++ {
+			if section.Field(j).Kind() == reflect.Map {
+				continue
+			}
+			key := section.Type().Field(j).Tag.Get("toml")
+			name := sectionName + "." + key
+			settings = append(settings, setting{
+				name:  name,
+				env:   envPrefix + strings.ToUpper(strings.ReplaceAll(name, ".", "_")),
+				value: section.Field(j),
+			})
+		}
+	}
+	return settings
+}
+
+func (c *Config) setting(name string) (setting, bool) {
+	for _, s := range c.settings() {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// set parses value into the setting.
+func (s setting) set(value string) error {
+	switch s.value.Kind() {
+	case reflect.String:
+		s.value.SetString(value)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		s.value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		s.value.SetBool(b)
+	case reflect.Slice:
+		// Lists are given comma separated
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("cannot be set from a string")
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"CodeXecutor/models"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeConfig writes a configuration file for a test and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), FileName)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644), "Error creating test config file")
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+		[redis]
+		addr = "redis:6379"
+		password = ""
+		db = 0
+		pool_size = 20
+		min_idle_conns = 5
+
+		[languages.java]
+		image = "openjdk:17"
+	`)
+
+	config, err := Load(path, nil)
+	assert.NoError(t, err, "Error loading config")
+	assert.Equal(t, "redis:6379", config.Redis.Addr, "Incorrect Redis address in config")
+	assert.Equal(t, 20, config.Redis.PoolSize, "Snake case keys should be loaded")
+	assert.Equal(t, Default().Workers, config.Workers, "Settings left out should keep their defaults")
+	assert.Equal(t, 2, config.Languages["java"].MaxConcurrent, "Languages should keep the default limit")
+	assert.Equal(t, filepath.Dir(path), config.dir, "Other config files should be looked up next to the file")
+
+	_, err = Load(writeConfig(t, "[redis]\nPoolSize = 10\n"), nil)
+	assert.Error(t, err, "Unknown settings should be rejected")
+}
+
+func TestLoadOverrides(t *testing.T) {
+	path := writeConfig(t, "[server]\naddr = \"localhost:8080\"\n")
+	t.Setenv("CODEXECUTOR_SERVER_ADDR", "0.0.0.0:9090")
+	t.Setenv("CODEXECUTOR_WORKERS_MAX", "6")
+
+	config, err := Load(path, map[string]string{"workers.max": "8"})
+	assert.NoError(t, err, "Error loading config")
+	assert.Equal(t, "0.0.0.0:9090", config.Server.Addr, "The environment should override the file")
+	assert.Equal(t, 8, config.Workers.Max, "Flags should override the environment")
+
+	_, err = Load(path, map[string]string{"workers.max": "many"})
+	assert.Error(t, err, "Values of the wrong type should be rejected")
+	_, err = Load(path, map[string]string{"workers.min": "0", "redis.addr": "localhost"})
+	assert.ErrorContains(t, err, "workers.min", "Invalid settings should be rejected")
+	assert.ErrorContains(t, err, "redis.addr", "Every invalid setting should be reported")
+
+	config, err = Load(path, map[string]string{"redis.mode": "cluster", "redis.addrs": "node-1:6379, node-2:6379"})
+	assert.NoError(t, err, "Error loading config")
+	assert.Equal(t, []string{"node-1:6379", "node-2:6379"}, config.Redis.Addrs, "Lists should be comma separated")
+	_, err = Load(path, map[string]string{"redis.mode": "sentinel", "redis.addrs": "sentinel:26379"})
+	assert.ErrorContains(t, err, "redis.master_name", "Sentinels need the name of the master")
+}
+
+func TestLanguageTable(t *testing.T) {
+	config := Default()
+	config.Languages["python"] = LanguageConfig{Image: "python:3.12", TimeoutSeconds: 5}
+	config.Languages["ruby"] = LanguageConfig{Image: "ruby:3.3", MemoryMB: 128, TimeoutSeconds: 2, Run: []string{"ruby", "{entrypoint}"}, SourceFile: "main.rb"}
+	assert.NoError(t, config.Validate())
+
+	languages := config.LanguageTable()
+	assert.Equal(t, "python:3.12", languages["python"].Image)
+	assert.Equal(t, 5*time.Second, languages["python"].Timeout)
+	assert.Equal(t, models.Languages["python"].Run, languages["python"].Run, "Settings left out should keep their built-in value")
+	assert.Equal(t, int64(128<<20), languages["ruby"].Memory)
+
+	config.Languages["cobol"] = LanguageConfig{Image: "cobol"}
+	assert.Error(t, config.Validate(), "New languages need a command and a source file")
+}
+
+// testFile is the configuration of a registered file in tests.
+type testFile struct {
+	Store struct {
+		URL         string `toml:"url"`
+		TTLSeconds  int    `toml:"ttl_seconds"`
+		MaxAttempts int    `toml:"max_attempts"`
+	} `toml:"store"`
+}
+
+func (f *testFile) Validate() error {
+	if f.Store.TTLSeconds < 1 {
+		return errors.New("store.ttl_seconds: must be at least 1")
+	}
+	return nil
+}
+
+func TestLoadRegisteredFiles(t *testing.T) {
+	Register("store.toml", func() Validator {
+		file := &testFile{}
+		file.Store.TTLSeconds, file.Store.MaxAttempts = 15, 3
+		return file
+	})
+	defer delete(registered, "store.toml")
+
+	path := writeConfig(t, "")
+	assert.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "store.toml"), []byte("[store]\nurl = \"file\"\nmax_attempts = 5\n"), 0644))
+	t.Setenv("CODEXECUTOR_STORE_URL", "env")
+
+	config, err := Load(path, map[string]string{"store.max_attempts": "7"})
+	assert.NoError(t, err, "Error loading config")
+	file := config.files["store.toml"].(*testFile)
+	assert.Equal(t, 15, file.Store.TTLSeconds, "Settings left out of the file should keep their defaults")
+	assert.Equal(t, "env", file.Store.URL, "The environment should override the file")
+	assert.Equal(t, 7, file.Store.MaxAttempts, "Flags should override the file")
+
+	_, err = Load(path, map[string]string{"store.ttl_seconds": "0"})
+	assert.ErrorContains(t, err, "store.toml: store.ttl_seconds", "Invalid settings of a file should be rejected naming the file")
+
+	assert.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "store.toml"), []byte("[store]\nttl = 5\n"), 0644))
+	_, err = Load(path, nil)
+	assert.ErrorContains(t, err, "unknown settings", "Unknown settings of a file should be rejected")
+}
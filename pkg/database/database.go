@@ -1,14 +1,107 @@
 package database
 
-// import (
-// 	"database/sql"
-// 	// _ "github.com/driver/sql-driver/mysql"
-// )
-
-// func ConnectDB() (*sql.DB, error) {
-// 	// Establish a connection to your database
-// }
-
-// func StoreCodeResults(db *sql.DB, code, results string) error {
-// 	// Store code and results in the database
-// }
+import (
+	"CodeXecutor/models"
+	appConfig "CodeXecutor/pkg/config"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned for jobs the store does not have.
+var ErrNotFound = errors.New("job not found")
+
+type DatabaseConfig struct {
+	Driver string `toml:"driver"` // "sqlite" or "postgres"
+	DSN    string `toml:"dsn"`    // Data source name, a file name for SQLite or a connection URL for Postgres
+}
+
+type RetentionConfig struct {
+	CacheSeconds           int `toml:"cache_seconds"`            // How long results stay cached in Redis
+	ResultDays             int `toml:"result_days"`              // How long finished jobs are kept in the database, 0 to keep them forever
+	CleanupIntervalMinutes int `toml:"cleanup_interval_minutes"` // How often expired jobs are deleted
+}
+
+type Config struct {
+	Database  DatabaseConfig  `toml:"database"`
+	Retention RetentionConfig `toml:"retention"`
+}
+
+// CacheTTL returns how long results stay cached in Redis.
+func (retention RetentionConfig) CacheTTL() time.Duration {
+	return time.Duration(retention.CacheSeconds) * time.Second
+}
+
+// JobRecord is a job as persisted, with its result once it finished.
+type JobRecord struct {
+	Job         models.Job
+	Status      string
+	Result      *models.CompilationResult
+	SubmittedAt time.Time
+	FinishedAt  time.Time // Zero until the job finished
+}
+
+// Store persists submissions and their results beyond the lifetime of the Redis cache.
+type Store interface {
+	// SaveJobs records submitted jobs as queued.
+	SaveJobs(ctx context.Context, jobs ...models.Job) error
+	// SetStatus updates the status of an unfinished job.
+	SetStatus(ctx context.Context, tenant, jobID, status string) error
+	// SaveResult records the result of a job, recording the job as well if it is missing.
+	SaveResult(ctx context.Context, job models.Job, result models.CompilationResult) error
+	// GetJob returns a job of a tenant, or ErrNotFound.
+	GetJob(ctx context.Context, tenant, jobID string) (JobRecord, error)
+	// ListJobs returns the jobs matching a filter, newest first, and the cursor of the next page, empty on the last page.
+	ListJobs(ctx context.Context, filter JobFilter) ([]JobSummary, string, error)
+	// DeleteFinishedBefore deletes the jobs finished before a time and returns how many were deleted.
+	DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error)
+	Close() error
+}
+
+func init() {
+	appConfig.Register("database.toml", func() appConfig.Validator {
+		return &Config{
+			Database:  DatabaseConfig{Driver: "sqlite", DSN: "codexecutor.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"},
+			Retention: RetentionConfig{CacheSeconds: 15, ResultDays: 30, CleanupIntervalMinutes: 60},
+		}
+	})
+}
+
+// GetConfig returns the database configuration, loaded and validated with the configuration file.
+func GetConfig() *Config {
+	return appConfig.Registered[*Config]("database.toml")
+}
+
+// Validate checks that the settings are usable, reporting every invalid one.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Database.Driver != "sqlite" && c.Database.Driver != "postgres" {
+		errs = append(errs, fmt.Errorf("database.driver: must be sqlite or postgres, not %q", c.Database.Driver))
+	}
+	if c.Database.DSN == "" {
+		errs = append(errs, errors.New("database.dsn: is required"))
+	}
+	if c.Retention.CacheSeconds < 1 {
+		errs = append(errs, errors.New("retention.cache_seconds: must be at least 1"))
+	}
+	if c.Retention.ResultDays < 0 {
+		errs = append(errs, errors.New("retention.result_days: must not be negative"))
+	}
+	if c.Retention.CleanupIntervalMinutes < 1 {
+		errs = append(errs, errors.New("retention.cleanup_interval_minutes: must be at least 1"))
+	}
+	return errors.Join(errs...)
+}
+
+// Open connects to the configured database and migrates its schema.
+func Open(config DatabaseConfig) (Store, error) {
+	switch config.Driver {
+	case "sqlite", "":
+		return openSQL(sqlite, config.DSN)
+	case "postgres":
+		return openSQL(postgres, config.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", config.Driver)
+	}
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := Open(DatabaseConfig{Driver: "sqlite", DSN: filepath.Join(t.TempDir(), "test.db")})
+	assert.NoError(t, err, "Error opening database")
+	defer store.Close()
+
+	job := models.Job{ID: "job-1", Tenant: "test", Language: "python", Code: "print(1)"}
+	assert.NoError(t, store.SaveJobs(ctx, job), "Error saving job")
+	assert.NoError(t, store.SetStatus(ctx, "test", "job-1", models.StatusRunning), "Error setting status")
+
+	record, err := store.GetJob(ctx, "test", "job-1")
+	assert.NoError(t, err, "Error getting job")
+	assert.Equal(t, job, record.Job)
+	assert.Equal(t, models.StatusRunning, record.Status)
+	assert.Nil(t, record.Result, "Unfinished jobs have no result")
+
+	result := models.CompilationResult{Status: models.StatusCompleted, ExitCode: 1, Output: "1\n", Error: errors.New("exit status 1")}
+	assert.NoError(t, store.SaveResult(ctx, job, result), "Error saving result")
+
+	record, err = store.GetJob(ctx, "test", "job-1")
+	assert.NoError(t, err, "Error getting job")
+	assert.Equal(t, &result, record.Result)
+
+	// Jobs are scoped to their tenant
+	_, err = store.GetJob(ctx, "other", "job-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	deleted, err := store.DeleteFinishedBefore(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err, "Error deleting jobs")
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestRebind(t *testing.T) {
+	query := `UPDATE jobs SET status = ? WHERE tenant = ? AND id = ?`
+
+	assert.Equal(t, query, sqlite.rebind(query))
+	assert.Equal(t, `UPDATE jobs SET status = $1 WHERE tenant = $2 AND id = $3`, postgres.rebind(query))
+}
+
+func TestListJobs(t *testing.T) {
+	ctx := context.Background()
+	store, err := Open(DatabaseConfig{Driver: "sqlite", DSN: filepath.Join(t.TempDir(), "test.db")})
+	assert.NoError(t, err, "Error opening database")
+	defer store.Close()
+
+	for i, language := range []string{"python", "python", "node", "python", "python"} {
+		job := models.Job{ID: fmt.Sprintf("job-%d", i), Tenant: "test", Language: language}
+		assert.NoError(t, store.SaveJobs(ctx, job), "Error saving job")
+		assert.NoError(t, store.SaveResult(ctx, job, models.CompilationResult{Status: models.StatusCompleted, ExitCode: i % 2}), "Error saving result")
+	}
+	assert.NoError(t, store.SaveJobs(ctx, models.Job{ID: "other-job", Tenant: "other", Language: "python"}), "Error saving job")
+
+	// Pages of two python jobs of the tenant, newest first
+	filter := JobFilter{Tenant: "test", Language: "python", Limit: 2}
+	var ids []string
+	for {
+		jobs, next, err := store.ListJobs(ctx, filter)
+		assert.NoError(t, err, "Error listing jobs")
+		for _, job := range jobs {
+			ids = append(ids, job.ID)
+		}
+		if next == "" {
+			break
+		}
+		filter.Cursor = next
+	}
+	assert.Equal(t, []string{"job-4", "job-3", "job-1", "job-0"}, ids)
+
+	exitCode := 1
+	jobs, _, err := store.ListJobs(ctx, JobFilter{Verdict: models.VerdictRuntimeError, ExitCode: &exitCode})
+	assert.NoError(t, err, "Error listing jobs")
+	assert.Len(t, jobs, 2, "Jobs 1 and 3 exited with 1")
+
+	_, _, err = store.ListJobs(ctx, JobFilter{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for cursors that were not returned by ListJobs.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	DefaultPageSize = 50  // Jobs returned by ListJobs when the filter sets no limit
+	MaxPageSize     = 200 // Most jobs returned by ListJobs at once
+)
+
+// JobFilter selects the jobs returned by ListJobs. Zero fields do not filter.
+type JobFilter struct {
+	Tenant   string
+	Language string
+	Status   string
+	Verdict  string
+	ExitCode *int
+	From     time.Time // Submitted at or after
+	To       time.Time // Submitted before
+	Cursor   string    // Cursor of the page to return, as returned by ListJobs
+	Limit    int
+}
+
+// JobSummary is a job of the history, without its code and output.
+type JobSummary struct {
+	Tenant      string     `json:"tenant"`
+	ID          string     `json:"id"`
+	Language    string     `json:"language"`
+	BatchID     string     `json:"batch_id,omitempty"`
+	Status      string     `json:"status"`
+	Verdict     string     `json:"verdict,omitempty"`
+	ExitCode    *int       `json:"exitcode"`
+	SubmittedAt time.Time  `json:"submitted_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+}
+
+// cursor is the position after the last job of a page, in the order of ListJobs.
+type cursor struct {
+	SubmittedAt int64  `json:"s"`
+	Tenant      string `json:"t"`
+	ID          string `json:"i"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &c) != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func (s *sqlStore) ListJobs(ctx context.Context, filter JobFilter) ([]JobSummary, string, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, values ...interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, values...)
+	}
+
+	if filter.Tenant != "" {
+		where("tenant = ?", filter.Tenant)
+	}
+	if filter.Language != "" {
+		where("language = ?", filter.Language)
+	}
+	if filter.Status != "" {
+		where("status = ?", filter.Status)
+	}
+	if filter.Verdict != "" {
+		where("verdict = ?", filter.Verdict)
+	}
+	if filter.ExitCode != nil {
+		where("exit_code = ?", *filter.ExitCode)
+	}
+	if !filter.From.IsZero() {
+		where("submitted_at >= ?", filter.From.UnixMilli())
+	}
+	if !filter.To.IsZero() {
+		where("submitted_at < ?", filter.To.UnixMilli())
+	}
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		// Jobs are ordered by submission time, ties broken by tenant and ID
+		where("(submitted_at < ? OR (submitted_at = ? AND (tenant < ? OR (tenant = ? AND id < ?))))",
+			c.SubmittedAt, c.SubmittedAt, c.Tenant, c.Tenant, c.ID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	limit = min(limit, MaxPageSize)
+
+	query := `SELECT tenant, id, language, batch_id, status, verdict, exit_code, submitted_at, finished_at FROM jobs`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// One more job than requested tells whether there is a next page
+	query += " ORDER BY submitted_at DESC, tenant DESC, id DESC LIMIT ?"
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	jobs := []JobSummary{}
+	var submittedAt []int64
+	for rows.Next() {
+		var (
+			job        JobSummary
+			verdict    sql.NullString
+			exitCode   sql.NullInt64
+			submitted  int64
+			finishedAt sql.NullInt64
+		)
+		if err := rows.Scan(&job.Tenant, &job.ID, &job.Language, &job.BatchID, &job.Status, &verdict, &exitCode, &submitted, &finishedAt); err != nil {
+			return nil, "", err
+		}
+
+		job.Verdict = verdict.String
+		job.SubmittedAt = time.UnixMilli(submitted)
+		if finishedAt.Valid {
+			finished := time.UnixMilli(finishedAt.Int64)
+			job.FinishedAt = &finished
+			code := int(exitCode.Int64)
+			job.ExitCode = &code
+		}
+
+		jobs = append(jobs, job)
+		submittedAt = append(submittedAt, submitted)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(jobs) <= limit {
+		return jobs, "", nil
+	}
+
+	last := jobs[limit-1]
+	next := encodeCursor(cursor{SubmittedAt: submittedAt[limit-1], Tenant: last.Tenant, ID: last.ID})
+	return jobs[:limit], next, nil
+}
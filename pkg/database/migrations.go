@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// migrations are the schema changes of the database, applied in order and each only once.
+// Released migrations must not be edited; change the schema by appending a new one.
+var migrations = [][]string{
+	// 1: jobs with their results
+	{
+		`CREATE TABLE jobs (
+			tenant       TEXT NOT NULL,
+			id           TEXT NOT NULL,
+			language     TEXT NOT NULL,
+			batch_id     TEXT NOT NULL DEFAULT '',
+			job          TEXT NOT NULL,
+			status       TEXT NOT NULL,
+			submitted_at BIGINT NOT NULL,
+			finished_at  BIGINT,
+			exit_code    INTEGER,
+			output       TEXT,
+			error        TEXT,
+			PRIMARY KEY (tenant, id)
+		)`,
+		`CREATE INDEX jobs_finished_at ON jobs (finished_at)`,
+		`CREATE INDEX jobs_tenant_submitted_at ON jobs (tenant, submitted_at)`,
+	},
+	// 2: verdicts of finished jobs, for searching the history
+	{
+		`ALTER TABLE jobs ADD COLUMN verdict TEXT`,
+		`UPDATE jobs SET verdict = CASE
+			WHEN status = 'cancelled' THEN 'cancelled'
+			WHEN error IS NOT NULL THEN 'system_error'
+			WHEN exit_code <> 0 THEN 'runtime_error'
+			ELSE 'success'
+		END
+		WHERE finished_at IS NOT NULL`,
+		`CREATE INDEX jobs_submitted_at ON jobs (submitted_at)`,
+	},
+}
+
+// migrate applies the migrations the database is missing.
+func (s *sqlStore) migrate(ctx context.Context) error {
+	if _, err := s.exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for i := current; i < len(migrations); i++ {
+		version := i + 1
+		if err := s.apply(ctx, version, migrations[i]); err != nil {
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		slog.Info("Applied database migration", "version", version)
+	}
+
+	return nil
+}
+
+// apply runs the statements of a migration and records it in a single transaction.
+func (s *sqlStore) apply(ctx context.Context, version int, statements []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`), version, time.Now().Unix()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
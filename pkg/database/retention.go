@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RunRetention deletes the jobs of store finished longer ago than the retention period until ctx is done.
+func RunRetention(ctx context.Context, store Store) {
+	retention := GetConfig().Retention
+	if retention.ResultDays <= 0 {
+		return
+	}
+
+	interval := time.Duration(retention.CleanupIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		before := time.Now().AddDate(0, 0, -retention.ResultDays)
+		if deleted, err := store.DeleteFinishedBefore(ctx, before); err != nil {
+			slog.Error("Error deleting expired jobs", "error", err)
+		} else if deleted > 0 {
+			slog.Info("Deleted expired jobs", "count", deleted, "finished_before", before.Format(time.RFC3339))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
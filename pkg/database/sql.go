@@ -0,0 +1,199 @@
+package database
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// dialect holds what differs between the supported SQL databases.
+type dialect struct {
+	driver   string // Name of the database/sql driver
+	numbered bool   // Whether placeholders are numbered ($1) rather than ?
+}
+
+var (
+	sqlite   = dialect{driver: "sqlite"}
+	postgres = dialect{driver: "pgx", numbered: true}
+)
+
+// rebind rewrites the ? placeholders of a query for the dialect.
+func (d dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// sqlStore is a Store backed by a SQL database.
+type sqlStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// openSQL connects to a SQL database and migrates its schema.
+func openSQL(d dialect, dsn string) (Store, error) {
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer, queuing in the pool beats failing with "database is locked"
+	if d == sqlite {
+		db.SetMaxOpenConns(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := &sqlStore{db: db, dialect: d}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *sqlStore) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.db.ExecContext(ctx, s.dialect.rebind(query), args...)
+}
+
+func (s *sqlStore) SaveJobs(ctx context.Context, jobs ...models.Job) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, s.dialect.rebind(`
+		INSERT INTO jobs (tenant, id, language, batch_id, job, status, submitted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tenant, id) DO NOTHING`))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UnixMilli()
+	for _, job := range jobs {
+		data, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, job.Tenant, job.ID, job.Language, job.BatchID, string(data), models.StatusQueued, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlStore) SetStatus(ctx context.Context, tenant, jobID, status string) error {
+	_, err := s.exec(ctx, `UPDATE jobs SET status = ? WHERE tenant = ? AND id = ? AND finished_at IS NULL`, status, tenant, jobID)
+	return err
+}
+
+func (s *sqlStore) SaveResult(ctx context.Context, job models.Job, result models.CompilationResult) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	var errorMessage sql.NullString
+	if result.Error != nil {
+		errorMessage = sql.NullString{String: result.Error.Error(), Valid: true}
+	}
+
+	now := time.Now().UnixMilli()
+	_, err = s.exec(ctx, `
+		INSERT INTO jobs (tenant, id, language, batch_id, job, status, submitted_at, finished_at, exit_code, output, error, verdict)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tenant, id) DO UPDATE SET
+			status = excluded.status,
+			finished_at = excluded.finished_at,
+			exit_code = excluded.exit_code,
+			output = excluded.output,
+			error = excluded.error,
+			verdict = excluded.verdict`,
+		job.Tenant, job.ID, job.Language, job.BatchID, string(data), result.Status, now, now, result.ExitCode, result.Output, errorMessage, result.Verdict())
+	return err
+}
+
+func (s *sqlStore) GetJob(ctx context.Context, tenant, jobID string) (JobRecord, error) {
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`
+		SELECT job, status, submitted_at, finished_at, exit_code, output, error
+		FROM jobs WHERE tenant = ? AND id = ?`), tenant, jobID)
+
+	record, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return JobRecord{}, ErrNotFound
+	}
+	return record, err
+}
+
+func (s *sqlStore) DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.exec(ctx, `DELETE FROM jobs WHERE finished_at < ?`, before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// scanJob reads a job selected as job, status, submitted_at, finished_at, exit_code, output, error.
+func scanJob(row interface{ Scan(...interface{}) error }) (JobRecord, error) {
+	var (
+		record       JobRecord
+		data         string
+		submittedAt  int64
+		finishedAt   sql.NullInt64
+		exitCode     sql.NullInt64
+		output       sql.NullString
+		errorMessage sql.NullString
+	)
+	if err := row.Scan(&data, &record.Status, &submittedAt, &finishedAt, &exitCode, &output, &errorMessage); err != nil {
+		return JobRecord{}, err
+	}
+
+	if err := json.Unmarshal([]byte(data), &record.Job); err != nil {
+		return JobRecord{}, err
+	}
+	record.SubmittedAt = time.UnixMilli(submittedAt)
+
+	if finishedAt.Valid {
+		record.FinishedAt = time.UnixMilli(finishedAt.Int64)
+		record.Result = &models.CompilationResult{Status: record.Status, ExitCode: int(exitCode.Int64), Output: output.String}
+		if errorMessage.Valid {
+			record.Result.Error = errors.New(errorMessage.String)
+		}
+	}
+
+	return record, nil
+}
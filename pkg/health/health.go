@@ -0,0 +1,94 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// timeout bounds how long a single check may take before its component is reported as failing.
+const timeout = 2 * time.Second
+
+// Statuses of a component.
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+)
+
+// Check reports whether a component works, returning the reason if it does not.
+type Check func(ctx context.Context) error
+
+// Component is the result of the check of a component.
+type Component struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+var (
+	mu     sync.Mutex
+	checks = map[string]Check{}
+)
+
+// Register adds a component to the readiness checks.
+// Registering a component again replaces its check.
+func Register(name string, check Check) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks[name] = check
+}
+
+// Ready runs all checks at once and reports whether every component works, with the result of each.
+// Without any registered component the service is not ready.
+func Ready(ctx context.Context) (bool, map[string]Component) {
+	mu.Lock()
+	registered := make(map[string]Check, len(checks))
+	for name, check := range checks {
+		registered[name] = check
+	}
+	mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var resultsMu sync.Mutex
+	results := make(map[string]Component, len(registered))
+	for name, check := range registered {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			started := time.Now()
+			component := Component{Status: StatusOK}
+			if err := run(ctx, check); err != nil {
+				component = Component{Status: StatusFailing, Error: err.Error()}
+			}
+			component.LatencyMs = time.Since(started).Milliseconds()
+
+			resultsMu.Lock()
+			results[name] = component
+			resultsMu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	ready := len(results) > 0
+	for _, component := range results {
+		ready = ready && component.Status == StatusOK
+	}
+	return ready, results
+}
+
+// run runs a check, giving up once ctx is done even if the check ignores it.
+func run(ctx context.Context, check Check) error {
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,34 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady(t *testing.T) {
+	checks = map[string]Check{}
+	ready, _ := Ready(context.Background())
+	assert.False(t, ready, "Without components the service should not be ready")
+
+	Register("redis", func(context.Context) error { return nil })
+	ready, components := Ready(context.Background())
+	assert.True(t, ready)
+	assert.Equal(t, StatusOK, components["redis"].Status)
+
+	Register("docker", func(context.Context) error { return errors.New("daemon not running") })
+	ready, components = Ready(context.Background())
+	assert.False(t, ready, "A failing component should make the service not ready")
+	assert.Equal(t, StatusOK, components["redis"].Status)
+	assert.Equal(t, Component{Status: StatusFailing, Error: "daemon not running", LatencyMs: components["docker"].LatencyMs}, components["docker"])
+
+	// A check that hangs fails once the timeout expires
+	Register("docker", func(context.Context) error { select {} })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ready, components = Ready(ctx)
+	assert.False(t, ready)
+	assert.Equal(t, context.Canceled.Error(), components["docker"].Error)
+}
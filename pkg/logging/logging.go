@@ -0,0 +1,92 @@
+package logging
+
+import (
+	appConfig "CodeXecutor/pkg/config"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type LoggingConfig struct {
+	Level  string `toml:"level"`  // Lowest level logged: "debug", "info", "warn" or "error"
+	Format string `toml:"format"` // "json" for one JSON object per line, or "text"
+}
+
+type Config struct {
+	Logging LoggingConfig `toml:"logging"`
+}
+
+func init() {
+	appConfig.Register("logging.toml", func() appConfig.Validator {
+		return &Config{Logging: LoggingConfig{Level: "info", Format: "json"}}
+	})
+}
+
+// GetConfig returns the logging configuration, loaded and validated with the configuration file.
+func GetConfig() *Config {
+	return appConfig.Registered[*Config]("logging.toml")
+}
+
+// Validate checks that the level and format are known.
+func (c *Config) Validate() error {
+	if _, err := New(io.Discard, c.Logging); err != nil {
+		return fmt.Errorf("logging: %w", err)
+	}
+	return nil
+}
+
+// Setup makes the configured logger the default of log/slog, and of the log package with it.
+func Setup() error {
+	logger, err := New(os.Stdout, GetConfig().Logging)
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(logger)
+	return nil
+}
+
+// New returns a logger writing to w at the configured level and format.
+func New(w io.Writer, config LoggingConfig) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	if config.Level != "" {
+		if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q: expected debug, info, warn or error", config.Level)
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(config.Format) {
+	case "json", "":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q: expected json or text", config.Format)
+	}
+}
+
+// Fatal logs an error and exits, like log.Fatal but at the error level.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type contextKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger, see FromContext.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, holding the IDs of the request or job it logs for,
+// or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
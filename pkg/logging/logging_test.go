@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, LoggingConfig{Level: "warn", Format: "json"})
+	assert.NoError(t, err, "Error creating logger")
+
+	logger.Info("dropped")
+	logger.Warn("kept", "job_id", "abc")
+
+	var line map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &line), "Only the warning should be logged, as JSON")
+	assert.Equal(t, "kept", line["msg"])
+	assert.Equal(t, "WARN", line["level"])
+	assert.Equal(t, "abc", line["job_id"])
+
+	_, err = New(&buf, LoggingConfig{Level: "verbose"})
+	assert.Error(t, err, "Unknown levels should be rejected")
+	_, err = New(&buf, LoggingConfig{Format: "xml"})
+	assert.Error(t, err, "Unknown formats should be rejected")
+}
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, LoggingConfig{})
+	ctx := WithLogger(context.Background(), logger.With("request_id", "r1"))
+
+	FromContext(ctx).Info("hello")
+	assert.Contains(t, buf.String(), `"request_id":"r1"`, "The logger of the context should be used")
+	assert.NotNil(t, FromContext(context.Background()), "Without a logger the default should be returned")
+}
@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"CodeXecutor/models"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the names of all metrics of the service.
+const namespace = "codexecutor"
+
+// Statuses of code submissions counted by Submissions.
+const (
+	SubmissionQueued   = "queued"   // accepted and enqueued
+	SubmissionRejected = "rejected" // failed validation
+	SubmissionFailed   = "failed"   // could not be enqueued
+	SubmissionCached   = "cached"   // answered with the cached result of an identical submission
+)
+
+// Stages of running a container counted by ContainerFailures.
+const (
+	StageCreate = "create"
+	StageCopy   = "copy"
+	StageStart  = "start"
+)
+
+var (
+	// Submissions counts code submissions by language and status.
+	Submissions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "submissions_total",
+		Help:      "Code submissions by language and status.",
+	}, []string{"language", "status"})
+
+	// StartLatency measures the time from taking a job off the queue until a worker starts it.
+	StartLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_start_latency_seconds",
+		Help:      "Time from dequeueing a job until a worker starts it.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"language"})
+
+	// ExecutionDuration measures how long the containers of jobs run, by language and job status.
+	ExecutionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "execution_duration_seconds",
+		Help:      "Execution time of jobs by language and status.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"language", "status"})
+
+	// ContainerFailures counts containers that could not be run, by the stage that failed.
+	ContainerFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "container_failures_total",
+		Help:      "Containers that failed to be created, prepared or started.",
+	}, []string{"stage"})
+
+	// ActiveWorkers is the number of workers handling a job.
+	ActiveWorkers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workers_active",
+		Help:      "Workers currently handling a job.",
+	})
+
+	// Workers is the number of workers in the pool.
+	Workers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workers",
+		Help:      "Workers in the pool.",
+	})
+
+	// MaxWorkers is the number of workers the pool may grow to.
+	MaxWorkers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workers_max",
+		Help:      "Workers the pool may grow to.",
+	})
+
+	// RedisErrors counts failed Redis commands by command name.
+	RedisErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redis_errors_total",
+		Help:      "Failed Redis commands by command.",
+	}, []string{"command"})
+
+	queues = &queueCollector{
+		desc:   prometheus.NewDesc(namespace+"_queue_depth", "Jobs waiting in a queue.", []string{"queue"}, nil),
+		depths: map[string]func() (int64, error){},
+	}
+)
+
+func init() {
+	prometheus.MustRegister(Submissions, StartLatency, ExecutionDuration, ContainerFailures,
+		ActiveWorkers, Workers, MaxWorkers, RedisErrors, queues)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Language returns the label of a submitted language, folding unsupported ones into "unknown"
+// so clients cannot create new series.
+func Language(language string) string {
+	if _, ok := models.LookupLanguage(language); ok {
+		return language
+	}
+	return "unknown"
+}
+
+// RegisterQueue reports the depth of a queue, read by depth whenever the metrics are collected.
+// Registering a queue again replaces its depth function.
+func RegisterQueue(name string, depth func() (int64, error)) {
+	queues.mu.Lock()
+	defer queues.mu.Unlock()
+	queues.depths[name] = depth
+}
+
+// queueCollector reads the depth of the registered queues at collection time,
+// so the metric is exact even for queues shared by several processes.
+type queueCollector struct {
+	desc   *prometheus.Desc
+	mu     sync.Mutex
+	depths map[string]func() (int64, error)
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, depth := range c.depths {
+		n, err := depth()
+		if err != nil {
+			// Leave the queue out rather than report a wrong depth
+			slog.Warn("Error reading queue depth", "queue", name, "error", err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), name)
+	}
+}
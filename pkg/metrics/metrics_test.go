@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguage(t *testing.T) {
+	assert.Equal(t, "python", Language("python"), "Supported languages should be kept")
+	assert.Equal(t, "unknown", Language("cobol"), "Unsupported languages should share one label")
+	assert.Equal(t, "unknown", Language(""), "Missing languages should share one label")
+}
+
+func TestRegisterQueue(t *testing.T) {
+	RegisterQueue("test-queue", func() (int64, error) { return 3, nil })
+	RegisterQueue("test-broken", func() (int64, error) { return 0, errors.New("unavailable") })
+
+	expected := `
+# HELP codexecutor_queue_depth Jobs waiting in a queue.
+# TYPE codexecutor_queue_depth gauge
+codexecutor_queue_depth{queue="test-queue"} 3
+`
+	assert.NoError(t, testutil.CollectAndCompare(queues, strings.NewReader(expected)), "Only readable queues should be reported")
+
+	// Registering a queue again replaces its depth
+	RegisterQueue("test-queue", func() (int64, error) { return 5, nil })
+	assert.Equal(t, 5.0, testutil.ToFloat64(queues), "The latest depth function should be used")
+}
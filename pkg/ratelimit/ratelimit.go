@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	appConfig "CodeXecutor/pkg/config"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `toml:"requests_per_second"` // Rate the token bucket of a client refills at
+	Burst             int     `toml:"burst"`               // Requests a client may make at once
+}
+
+type QuotaConfig struct {
+	DailyExecutionSeconds float64            `toml:"daily_execution_seconds"` // Execution time a tenant may use per day, 0 for no limit
+	Tenants               map[string]float64 `toml:"tenants"`                 // Daily execution seconds overriding the default per tenant
+}
+
+type Config struct {
+	RateLimit       RateLimitConfig `toml:"rate_limit"`        // Limit per client IP address, checked before authentication
+	TenantRateLimit RateLimitConfig `toml:"tenant_rate_limit"` // Limit per tenant, checked after authentication
+	Quota           QuotaConfig     `toml:"quota"`
+}
+
+func init() {
+	appConfig.Register("ratelimit.toml", func() appConfig.Validator {
+		return &Config{
+			RateLimit:       RateLimitConfig{RequestsPerSecond: 5, Burst: 20},
+			TenantRateLimit: RateLimitConfig{RequestsPerSecond: 20, Burst: 50},
+			Quota:           QuotaConfig{DailyExecutionSeconds: 3600},
+		}
+	})
+}
+
+// GetConfig returns the rate limit and quota configuration, loaded and validated with the configuration file.
+func GetConfig() *Config {
+	return appConfig.Registered[*Config]("ratelimit.toml")
+}
+
+// Validate checks that the settings are usable, reporting every invalid one.
+func (c *Config) Validate() error {
+	errs := append(c.RateLimit.validate("rate_limit"), c.TenantRateLimit.validate("tenant_rate_limit")...)
+	if c.Quota.DailyExecutionSeconds < 0 {
+		errs = append(errs, errors.New("quota.daily_execution_seconds: must not be negative"))
+	}
+	for tenant, limit := range c.Quota.Tenants {
+		if limit < 0 {
+			errs = append(errs, fmt.Errorf("quota.tenants.%s: must not be negative", tenant))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the token bucket settings of section.
+func (c RateLimitConfig) validate(section string) []error {
+	var errs []error
+	if c.RequestsPerSecond <= 0 {
+		errs = append(errs, fmt.Errorf("%s.requests_per_second: must be positive", section))
+	}
+	if c.Burst < 1 {
+		errs = append(errs, fmt.Errorf("%s.burst: must be at least 1", section))
+	}
+	return errs
+}
+
+// DailyLimit returns the execution seconds a tenant may use per day, 0 for no limit.
+func (quota QuotaConfig) DailyLimit(tenant string) float64 {
+	if limit, ok := quota.Tenants[tenant]; ok {
+		return limit
+	}
+	return quota.DailyExecutionSeconds
+}
+
+// QuotaReset returns when the daily quotas of the day of now reset, at midnight UTC.
+func QuotaReset(now time.Time) time.Time {
+	year, month, day := now.UTC().Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDailyLimit(t *testing.T) {
+	quota := QuotaConfig{DailyExecutionSeconds: 60, Tenants: map[string]float64{"premium": 600}}
+
+	assert.Equal(t, 600.0, quota.DailyLimit("premium"))
+	assert.Equal(t, 60.0, quota.DailyLimit("other"))
+}
+
+func TestQuotaReset(t *testing.T) {
+	now := time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), QuotaReset(now))
+}
+
+func TestValidate(t *testing.T) {
+	config := Config{
+		RateLimit:       RateLimitConfig{RequestsPerSecond: 0, Burst: 20},
+		TenantRateLimit: RateLimitConfig{RequestsPerSecond: 20, Burst: 0},
+	}
+
+	assert.EqualError(t, config.Validate(), "rate_limit.requests_per_second: must be positive\ntenant_rate_limit.burst: must be at least 1")
+}
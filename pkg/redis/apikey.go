@@ -0,0 +1,24 @@
+package redis
+
+import (
+	"context"
+)
+
+func apiKeyKey(keyHash string) string {
+	return "apikey:" + keyHash
+}
+
+// GetAPIKeyTenant returns the tenant of an API key given by its SHA-256 hash, or redis.Nil if the key is unknown.
+func (s *Store) GetAPIKeyTenant(ctx context.Context, keyHash string) (string, error) {
+	return s.client.Get(ctx, apiKeyKey(keyHash)).Result()
+}
+
+// SetAPIKeyTenant assigns an API key given by its SHA-256 hash to a tenant.
+func (s *Store) SetAPIKeyTenant(ctx context.Context, keyHash, tenant string) error {
+	return s.client.Set(ctx, apiKeyKey(keyHash), tenant, 0).Err()
+}
+
+// DeleteAPIKey revokes an API key given by its SHA-256 hash.
+func (s *Store) DeleteAPIKey(ctx context.Context, keyHash string) error {
+	return s.client.Del(ctx, apiKeyKey(keyHash)).Err()
+}
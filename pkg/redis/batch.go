@@ -0,0 +1,112 @@
+package redis
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// BatchExpiration is how long a batch and the results of its jobs are kept.
+const BatchExpiration = 24 * time.Hour
+
+func batchRecordKey(batchKey string) string {
+	return jobScopedKey("batch", batchKey)
+}
+
+func batchResultsKey(batchKey string) string {
+	return jobScopedKey("batch-results", batchKey)
+}
+
+// EnqueueBatch records a batch and enqueues all of its jobs in a single round trip.
+func (s *Store) EnqueueBatch(ctx context.Context, queueName string, batch models.Batch, jobs []models.Job) error {
+
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	// In a cluster the transaction is split by slot, each job's status and events are still written together
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, batchRecordKey(batch.Key()), data, BatchExpiration)
+		for _, job := range jobs {
+			setJobStatus(ctx, pipe, job.Key(), models.StatusQueued)
+		}
+		return pushJobs(ctx, pipe, queueName, false, jobs...)
+	})
+	return err
+}
+
+// GetBatch returns the batch with the given key, or redis.Nil if the batch is unknown.
+func (s *Store) GetBatch(ctx context.Context, batchKey string) (models.Batch, error) {
+	data, err := s.client.Get(ctx, batchRecordKey(batchKey)).Result()
+	if err != nil {
+		return models.Batch{}, err
+	}
+
+	var batch models.Batch
+	err = json.Unmarshal([]byte(data), &batch)
+	return batch, err
+}
+
+// SaveBatchResult keeps the result of a batch job, by job ID, for as long as the batch exists.
+func (s *Store) SaveBatchResult(ctx context.Context, batchKey, jobID string, result models.CompilationResult) error {
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, batchResultsKey(batchKey), jobID, data)
+		pipe.Expire(ctx, batchResultsKey(batchKey), BatchExpiration)
+		return nil
+	})
+	return err
+}
+
+// GetBatchResults returns the results of the finished jobs of a batch by job ID.
+func (s *Store) GetBatchResults(ctx context.Context, batchKey string) (map[string]models.CompilationResult, error) {
+	values, err := s.client.HGetAll(ctx, batchResultsKey(batchKey)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]models.CompilationResult, len(values))
+	for jobID, data := range values {
+		var result models.CompilationResult
+		if err := json.Unmarshal([]byte(data), &result); err != nil {
+			return nil, err
+		}
+		results[jobID] = result
+	}
+	return results, nil
+}
+
+// GetJobStatuses returns the current status of each job key, an empty string for unknown jobs.
+func (s *Store) GetJobStatuses(ctx context.Context, jobKeys []string) ([]string, error) {
+	if len(jobKeys) == 0 {
+		return nil, nil
+	}
+
+	// The keys of different jobs may be in different slots of a cluster, so they are read one by one in a pipeline
+	gets := make([]*redis.StringCmd, len(jobKeys))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, jobKey := range jobKeys {
+			gets[i] = pipe.Get(ctx, statusKey(jobKey))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	statuses := make([]string, len(gets))
+	for i, get := range gets {
+		statuses[i] = get.Val()
+	}
+	return statuses, nil
+}
@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnqueueBatch(t *testing.T) {
+	store := newTestStore(t)
+
+	jobs := []models.Job{
+		{ID: "batch-job-1", Language: "python", Code: "print(1)", BatchID: "test-batch", Tenant: "test"},
+		{ID: "batch-job-2", Language: "python", Code: "print(2)", BatchID: "test-batch", Tenant: "test"},
+	}
+	batch := models.Batch{ID: "test-batch", JobIDs: []string{"batch-job-1", "batch-job-2"}, Tenant: "test"}
+
+	err := store.EnqueueBatch(context.Background(), "test-batch-queue", batch, jobs)
+	assert.NoError(t, err, "Error enqueuing batch")
+
+	stored, err := store.GetBatch(context.Background(), batch.Key())
+	assert.NoError(t, err, "Error getting batch")
+	assert.Equal(t, batch, stored, "Stored batch does not match the enqueued batch")
+
+	statuses, err := store.GetJobStatuses(context.Background(), []string{jobs[0].Key(), jobs[1].Key()})
+	assert.NoError(t, err, "Error getting job statuses")
+	assert.Equal(t, []string{models.StatusQueued, models.StatusQueued}, statuses, "Batch jobs should be queued")
+
+	// Jobs are dequeued in submission order
+	for _, job := range jobs {
+		dequeued, err := store.DequeueItem(context.Background(), "test-batch-queue")
+		assert.NoError(t, err, "Error dequeuing item")
+		assert.Equal(t, job, dequeued, "Batch jobs should be dequeued in order")
+	}
+
+	result := models.CompilationResult{Status: models.StatusCompleted, Output: "1"}
+	err = store.SaveBatchResult(context.Background(), batch.Key(), "batch-job-1", result)
+	assert.NoError(t, err, "Error saving batch result")
+
+	results, err := store.GetBatchResults(context.Background(), batch.Key())
+	assert.NoError(t, err, "Error getting batch results")
+	assert.Equal(t, map[string]models.CompilationResult{"batch-job-1": result}, results, "Only finished jobs should have results")
+}
@@ -0,0 +1,33 @@
+package redis
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// resultCacheKey is the key of the cached result of a tenant's jobs with the given fingerprint,
+// see models.Job.Fingerprint. Tenants do not share results, so one cannot learn what another ran.
+func resultCacheKey(tenant, fingerprint string) string {
+	return "result-cache:" + tenant + ":" + fingerprint
+}
+
+// CacheResult stores the result of a job under its fingerprint, for identical jobs submitted within ttl.
+func (s *Store) CacheResult(ctx context.Context, tenant, fingerprint string, result models.CompilationResult, ttl time.Duration) error {
+	return s.SetCache(ctx, resultCacheKey(tenant, fingerprint), result, ttl)
+}
+
+// GetCachedResult returns the result stored for a tenant's jobs with the given fingerprint,
+// and reports whether there was one.
+func (s *Store) GetCachedResult(ctx context.Context, tenant, fingerprint string) (models.CompilationResult, bool, error) {
+	result, err := s.GetCache(ctx, resultCacheKey(tenant, fingerprint))
+	if errors.Is(err, redis.Nil) {
+		return models.CompilationResult{}, false, nil
+	} else if err != nil {
+		return models.CompilationResult{}, false, err
+	}
+	return result, true, nil
+}
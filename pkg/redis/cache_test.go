@@ -0,0 +1,33 @@
+package redis
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheResult(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	store.client.Del(ctx, resultCacheKey("tenant-a", "fingerprint"), resultCacheKey("tenant-b", "fingerprint"))
+
+	_, found, err := store.GetCachedResult(ctx, "tenant-a", "fingerprint")
+	assert.NoError(t, err, "A missing result should not be an error")
+	assert.False(t, found, "No result should be cached yet")
+
+	result := models.CompilationResult{Status: models.StatusCompleted, ExitCode: 1, Output: "boom"}
+	assert.NoError(t, store.CacheResult(ctx, "tenant-a", "fingerprint", result, time.Minute), "Error caching result")
+
+	cached, found, err := store.GetCachedResult(ctx, "tenant-a", "fingerprint")
+	assert.NoError(t, err, "Error reading cached result")
+	assert.True(t, found, "The result should be cached")
+	assert.Equal(t, result, cached, "The cached result should be the stored one")
+
+	// Results are not shared between tenants
+	_, found, err = store.GetCachedResult(ctx, "tenant-b", "fingerprint")
+	assert.NoError(t, err, "A missing result should not be an error")
+	assert.False(t, found, "Another tenant's result should not be returned")
+}
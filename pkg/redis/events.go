@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxEvents caps the number of events kept per job.
+const maxEvents = 10000
+
+func eventsKey(jobKey string) string {
+	return jobScopedKey("events", jobKey)
+}
+
+// AppendEvent appends an event to the event stream of a job.
+// The stream lives in Redis so clients can follow jobs running in any process.
+func (s *Store) AppendEvent(ctx context.Context, jobKey string, event models.Event) error {
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		appendEvent(ctx, pipe, jobKey, event)
+		return nil
+	})
+	return err
+}
+
+// appendEvent queues the commands appending an event to the event stream of a job.
+func appendEvent(ctx context.Context, pipe redis.Pipeliner, jobKey string, event models.Event) {
+	key := eventsKey(jobKey)
+
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: maxEvents,
+		Approx: true,
+		Values: map[string]interface{}{"type": event.Type, "data": event.Data},
+	})
+	pipe.Expire(ctx, key, StatusExpiration)
+}
+
+// ReadEvents returns the events of a job recorded after lastID, use "0" to read from the start.
+// It blocks up to block for new events and returns no events if none arrive in time.
+func (s *Store) ReadEvents(ctx context.Context, jobKey, lastID string, block time.Duration) ([]models.Event, error) {
+	streams, err := s.client.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{eventsKey(jobKey), lastID},
+		Block:   block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var events []models.Event
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			eventType, _ := message.Values["type"].(string)
+			data, _ := message.Values["data"].(string)
+			events = append(events, models.Event{ID: message.ID, Type: eventType, Data: data})
+		}
+	}
+	return events, nil
+}
+
+// appendResultEvent records the final result of a job as the last event of its stream.
+func (s *Store) appendResultEvent(ctx context.Context, jobKey string, result models.CompilationResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.AppendEvent(ctx, jobKey, models.Event{Type: models.EventResult, Data: string(data)})
+}
@@ -0,0 +1,180 @@
+package redis
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CancellationChannel is the pub/sub channel announcing the keys of cancelled jobs to all workers.
+const CancellationChannel = "job-cancellations"
+
+// StatusExpiration is how long a job status is kept after it was last updated.
+const StatusExpiration = time.Hour
+
+func statusKey(jobKey string) string {
+	return jobScopedKey("status", jobKey)
+}
+
+func cancelKey(jobKey string) string {
+	return jobScopedKey("cancelled", jobKey)
+}
+
+func resultKey(jobKey string) string {
+	return jobScopedKey("result", jobKey)
+}
+
+func resultChannel(jobKey string) string {
+	return "results:" + jobKey
+}
+
+// SetJobStatus records the current status of a job and appends the change to its event stream.
+func (s *Store) SetJobStatus(ctx context.Context, jobKey, status string) error {
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		setJobStatus(ctx, pipe, jobKey, status)
+		return nil
+	})
+	return err
+}
+
+// setJobStatus queues the commands recording the status of a job.
+func setJobStatus(ctx context.Context, pipe redis.Pipeliner, jobKey, status string) {
+	pipe.Set(ctx, statusKey(jobKey), status, StatusExpiration)
+	appendEvent(ctx, pipe, jobKey, models.Event{Type: models.EventStatus, Data: status})
+}
+
+// GetJobStatus returns the current status of a job, or redis.Nil if the job is unknown.
+// Like all functions of this package taking a job key, it expects the tenant scoped models.JobKey.
+func (s *Store) GetJobStatus(ctx context.Context, jobKey string) (string, error) {
+	return s.client.Get(ctx, statusKey(jobKey)).Result()
+}
+
+// RemoveItem removes the job with the given key while it is still waiting in the queue.
+// It returns the removed job and reports whether it was found and removed.
+func (s *Store) RemoveItem(ctx context.Context, queueName, jobKey string) (models.Job, bool, error) {
+	languages, err := s.QueueLanguages(ctx, queueName)
+	if err != nil {
+		return models.Job{}, false, err
+	}
+
+	for _, language := range languages {
+		key := languageQueueKey(queueName, language)
+		items, err := s.client.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return models.Job{}, false, err
+		}
+
+		for _, item := range items {
+			var job models.Job
+			if err := json.Unmarshal([]byte(item), &job); err != nil || job.Key() != jobKey {
+				continue
+			}
+
+			// The job may have been dequeued since the list was read
+			removed, err := s.client.LRem(ctx, key, 1, item).Result()
+			return job, removed > 0, err
+		}
+	}
+
+	return models.Job{}, false, nil
+}
+
+// CancelJob marks a job as cancelled and notifies the workers about it.
+// The mark lets a worker skip the job if it picks it up after the notification was sent.
+func (s *Store) CancelJob(ctx context.Context, jobKey string) error {
+	if err := s.client.Set(ctx, cancelKey(jobKey), 1, StatusExpiration).Err(); err != nil {
+		return err
+	}
+
+	return s.client.Publish(ctx, CancellationChannel, jobKey).Err()
+}
+
+// IsCancelled reports whether a job has been cancelled.
+func (s *Store) IsCancelled(ctx context.Context, jobKey string) (bool, error) {
+	n, err := s.client.Exists(ctx, cancelKey(jobKey)).Result()
+	return n > 0, err
+}
+
+// SubscribeCancellations subscribes to the keys of cancelled jobs.
+func (s *Store) SubscribeCancellations(ctx context.Context) *redis.PubSub {
+	return s.client.Subscribe(ctx, CancellationChannel)
+}
+
+// SaveResult stores the result of a job, records its final status and notifies anyone waiting for it.
+func (s *Store) SaveResult(ctx context.Context, jobKey string, result models.CompilationResult, expiration time.Duration) error {
+	if err := s.SetCache(ctx, resultKey(jobKey), result, expiration); err != nil {
+		return err
+	}
+
+	if err := s.SetJobStatus(ctx, jobKey, result.Status); err != nil {
+		return err
+	}
+
+	if err := s.appendResultEvent(ctx, jobKey, result); err != nil {
+		return err
+	}
+
+	return s.client.Publish(ctx, resultChannel(jobKey), result.Status).Err()
+}
+
+// WaitForResult returns the result of a job as soon as it is stored, waiting at most timeout for it.
+// If no result is stored in time, the returned error wraps redis.Nil. All waits of the store share
+// one subscription to the published results.
+func (s *Store) WaitForResult(ctx context.Context, jobKey string, timeout time.Duration) (models.CompilationResult, error) {
+	if timeout <= 0 {
+		return s.GetCache(ctx, resultKey(jobKey))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Wait before reading the cache so a result stored in between is not missed
+	published, stop, err := s.results.wait(ctx, s.client, jobKey)
+	if err != nil {
+		return models.CompilationResult{}, err
+	}
+	defer stop()
+
+	result, err := s.GetCache(ctx, resultKey(jobKey))
+	if !errors.Is(err, redis.Nil) {
+		return result, err
+	}
+
+	select {
+	case <-published:
+		return s.GetCache(ctx, resultKey(jobKey))
+	case <-ctx.Done():
+		return result, err
+	}
+}
+
+func stdinKey(jobKey string) string {
+	return jobScopedKey("stdin", jobKey)
+}
+
+// PushStdin queues input for the stdin of an interactive job.
+func (s *Store) PushStdin(ctx context.Context, jobKey, data string) error {
+	key := stdinKey(jobKey)
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, data)
+		pipe.Expire(ctx, key, StatusExpiration)
+		return nil
+	})
+	return err
+}
+
+// PopStdin waits up to timeout for input queued for an interactive job.
+// It returns redis.Nil if no input arrived in time.
+func (s *Store) PopStdin(ctx context.Context, jobKey string, timeout time.Duration) (string, error) {
+	result, err := s.client.BLPop(ctx, timeout, stdinKey(jobKey)).Result()
+	if err != nil {
+		return "", err
+	}
+	return result[1], nil
+}
@@ -0,0 +1,128 @@
+package redis
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveItem(t *testing.T) {
+	store := newTestStore(t)
+
+	job := models.Job{ID: "remove-me", Language: "python", Code: "print(1)", Tenant: "test"}
+	err := store.EnqueueItem(context.Background(), "test-remove-queue", job)
+	assert.NoError(t, err, "Error enqueuing item")
+
+	removedJob, removed, err := store.RemoveItem(context.Background(), "test-remove-queue", job.Key())
+	assert.NoError(t, err, "Error removing item")
+	assert.True(t, removed, "Queued job should be removed")
+	assert.Equal(t, job, removedJob, "Removed job should match the queued job")
+
+	_, removed, err = store.RemoveItem(context.Background(), "test-remove-queue", job.Key())
+	assert.NoError(t, err, "Error removing item")
+	assert.False(t, removed, "Job should no longer be in the queue")
+}
+
+func TestWaitForResult(t *testing.T) {
+	store := newTestStore(t)
+
+	key := "wait-for-result"
+	data := models.CompilationResult{Status: models.StatusCompleted, Output: "done"}
+	store.client.Del(context.Background(), resultKey(key))
+
+	saved := make(chan struct{})
+	go func() {
+		defer close(saved)
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(t, store.SaveResult(context.Background(), key, data, time.Minute), "Error saving result")
+	}()
+
+	result, err := store.WaitForResult(context.Background(), key, time.Second)
+	<-saved
+	assert.NoError(t, err, "Result should arrive before the timeout")
+	assert.Equal(t, data, result, "Waited result does not match the saved result")
+
+	status, err := store.GetJobStatus(context.Background(), key)
+	assert.NoError(t, err, "Error getting job status")
+	assert.Equal(t, models.StatusCompleted, status, "Status should be recorded with the result")
+}
+
+func TestWaitForResultTimeout(t *testing.T) {
+	store := newTestStore(t)
+
+	_, err := store.WaitForResult(context.Background(), "never-saved", 50*time.Millisecond)
+	assert.ErrorIs(t, err, redis.Nil, "Waiting for a missing result should time out as not found")
+	assert.Empty(t, store.results.waiters, "A request that stopped waiting should be unregistered")
+}
+
+func TestJobScopedKeys(t *testing.T) {
+	jobKey := models.JobKey("tenant", "id")
+	for _, key := range []string{statusKey(jobKey), cancelKey(jobKey), resultKey(jobKey), stdinKey(jobKey), eventsKey(jobKey), deliveryLogKey(jobKey)} {
+		assert.Contains(t, key, "{"+jobKey+"}", "Every key of a job should use the job key as hash tag")
+	}
+	assert.Equal(t, "status:{tenant:id}", statusKey(jobKey))
+}
+
+func TestWaitsStopOnCancel(t *testing.T) {
+	store := newTestStore(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := store.WaitForResult(ctx, "client-gone", 5*time.Second)
+	assert.ErrorIs(t, err, redis.Nil, "Waiting for a result should stop with the request")
+	assert.Less(t, time.Since(started), time.Second)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	started = time.Now()
+	_, err = store.DequeueItem(ctx, "test-empty-queue")
+	assert.ErrorIs(t, err, context.Canceled, "Waiting for a job should stop when the pool stops")
+	assert.Less(t, time.Since(started), 2*dequeueBlock)
+}
+
+func TestWaitForResultSharesSubscription(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	keys := make([]string, 20)
+	results := make([]chan models.CompilationResult, len(keys))
+	for i := range keys {
+		keys[i] = models.JobKey("test", fmt.Sprintf("shared-%d-%d", time.Now().UnixNano(), i))
+		results[i] = make(chan models.CompilationResult, 1)
+		go func(i int) {
+			result, err := store.WaitForResult(ctx, keys[i], 5*time.Second)
+			assert.NoError(t, err, "Result should arrive before the timeout")
+			results[i] <- result
+		}(i)
+	}
+
+	// All requests wait on the store's pattern subscription, none subscribes to its own channel
+	assert.Eventually(t, func() bool {
+		store.results.mu.Lock()
+		defer store.results.mu.Unlock()
+		return len(store.results.waiters) == len(keys)
+	}, 5*time.Second, 10*time.Millisecond, "Every request should wait for its result")
+	subscribers, err := store.client.PubSubNumSub(ctx, resultChannel(keys[0])).Result()
+	assert.NoError(t, err)
+	assert.Zero(t, subscribers[resultChannel(keys[0])], "A waiting request should not subscribe on its own")
+
+	for i, key := range keys {
+		assert.NoError(t, store.SaveResult(ctx, key, models.CompilationResult{Status: models.StatusCompleted, Output: key}, time.Minute))
+		select {
+		case result := <-results[i]:
+			assert.Equal(t, key, result.Output, "Each request should get the result of its own job")
+		case <-time.After(5 * time.Second):
+			t.Fatalf("The result of %s was not delivered", key)
+		}
+	}
+
+	store.results.mu.Lock()
+	defer store.results.mu.Unlock()
+	assert.Empty(t, store.results.waiters, "Delivered waiters should be unregistered")
+}
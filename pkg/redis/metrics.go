@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"CodeXecutor/pkg/metrics"
+	"context"
+	"errors"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// errorHook counts failed Redis commands. A missing key or an expired blocking pop is not a failure.
+type errorHook struct{}
+
+func (errorHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			metrics.RedisErrors.WithLabelValues("dial").Inc()
+		}
+		return conn, err
+	}
+}
+
+func (errorHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		countError(cmd, err)
+		return err
+	}
+}
+
+func (errorHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			countError(cmd, cmd.Err())
+		}
+		return err
+	}
+}
+
+func countError(cmd redis.Cmder, err error) {
+	if err != nil && !errors.Is(err, redis.Nil) && !errors.Is(err, context.Canceled) {
+		metrics.RedisErrors.WithLabelValues(cmd.Name()).Inc()
+	}
+}
+
+// QueueLength returns the number of jobs of all languages waiting in a queue.
+func (s *Store) QueueLength(ctx context.Context, queueName string) (int64, error) {
+	languages, err := s.QueueLanguages(ctx, queueName)
+	if err != nil {
+		return 0, err
+	}
+
+	cmds, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, language := range languages {
+			pipe.LLen(ctx, languageQueueKey(queueName, language))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var length int64
+	for _, cmd := range cmds {
+		length += cmd.(*redis.IntCmd).Val()
+	}
+	return length, nil
+}
+
+// PendingDeliveries returns the number of webhook deliveries waiting to be made.
+func (s *Store) PendingDeliveries(ctx context.Context) (int64, error) {
+	return s.client.ZCard(ctx, deliveriesKey).Result()
+}
@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// usageExpiration keeps a day's usage past its end so late jobs are still counted.
+const usageExpiration = 48 * time.Hour
+
+func rateLimitKey(client string) string {
+	return "ratelimit:" + client
+}
+
+func usageKey(tenant string, day time.Time) string {
+	return "usage:" + tenant + ":" + day.UTC().Format("2006-01-02")
+}
+
+// takeTokenScript refills a token bucket for the time passed since its last use and takes a token from it.
+// It runs on the Redis clock so API replicas with skewed clocks share the same buckets.
+var takeTokenScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1]) or burst
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate / 1000)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, math.floor(tokens), retry}
+`)
+
+// TakeToken takes a token from the bucket of a client, refilled at rate tokens per second up to burst tokens.
+// It reports whether the request is allowed, the tokens left and how long to wait for the next token otherwise.
+func (s *Store) TakeToken(ctx context.Context, client string, rate float64, burst int) (bool, int, time.Duration, error) {
+	res, err := takeTokenScript.Run(ctx, s.client, []string{rateLimitKey(client)}, rate, burst).Int64Slice()
+	if err != nil {
+		return false, 0, 0, err
+	}
+	return res[0] == 1, int(res[1]), time.Duration(res[2]) * time.Millisecond, nil
+}
+
+// AddExecutionSeconds adds to the execution time a tenant used on a day.
+func (s *Store) AddExecutionSeconds(ctx context.Context, tenant string, day time.Time, seconds float64) error {
+	key := usageKey(tenant, day)
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.IncrByFloat(ctx, key, seconds)
+		pipe.Expire(ctx, key, usageExpiration)
+		return nil
+	})
+	return err
+}
+
+// GetExecutionSeconds returns the execution time a tenant used on a day.
+func (s *Store) GetExecutionSeconds(ctx context.Context, tenant string, day time.Time) (float64, error) {
+	seconds, err := s.client.Get(ctx, usageKey(tenant, day)).Float64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return seconds, err
+}
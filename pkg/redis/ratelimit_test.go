@@ -0,0 +1,25 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTakeToken(t *testing.T) {
+	store := newTestStore(t)
+	store.client.Del(context.Background(), rateLimitKey("test-client"))
+
+	for i := 0; i < 3; i++ {
+		allowed, remaining, _, err := store.TakeToken(context.Background(), "test-client", 0.1, 3)
+		assert.NoError(t, err, "Error taking token")
+		assert.True(t, allowed, "Requests within the burst should be allowed")
+		assert.Equal(t, 2-i, remaining)
+	}
+
+	allowed, _, retryAfter, err := store.TakeToken(context.Background(), "test-client", 0.1, 3)
+	assert.NoError(t, err, "Error taking token")
+	assert.False(t, allowed, "Requests beyond the burst should be rejected")
+	assert.Greater(t, retryAfter.Seconds(), 9.0, "Retry should wait for the next token")
+}
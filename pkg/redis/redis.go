@@ -2,132 +2,233 @@ package redis
 
 import (
 	"CodeXecutor/models"
-	"CodeXecutor/utils"
+	"CodeXecutor/pkg/config"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"sync"
 	"time"
 
-	"github.com/BurntSushi/toml"
 	"github.com/redis/go-redis/v9"
 )
 
-type RedisConfig struct {
-	Addr            string
-	Password        string
-	DB              int
-	PoolSize        int
-	MinIdleConns    int
-	MaxRetries      int
-	MinRetryBackoff int
-	MaxRetryBackoff int
-}
+// dequeueBlock is how long a wait for a queued job blocks Redis before checking whether it was cancelled.
+const dequeueBlock = time.Second
 
-type Config struct {
-	Redis RedisConfig `toml:"redis"`
+// Store keeps the queue, the status, events and results of jobs and the other shared state in Redis.
+type Store struct {
+	client  redis.UniversalClient
+	results resultWaiters
 }
 
-var (
-	ConfigSingle *Config
-	once         sync.Once
-	clientPool   *redis.Client
-	err          error
-)
+// NewStore connects to Redis as configured.
+// Depending on its mode the store talks to a single server, to the master found through the sentinels,
+// or to the nodes of a cluster.
+func NewStore(ctx context.Context, redisConfig config.RedisConfig) (*Store, error) {
+	// Create a new Redis Options struct
+	options := &redis.UniversalOptions{
+		Addrs:            redisConfig.Addrs,
+		MasterName:       redisConfig.MasterName,
+		Username:         redisConfig.Username,
+		Password:         redisConfig.Password,
+		SentinelUsername: redisConfig.SentinelUsername,
+		SentinelPassword: redisConfig.SentinelPassword,
+		DB:               redisConfig.DB,
+		PoolSize:         redisConfig.PoolSize,
+		MinIdleConns:     redisConfig.MinIdleConns,
+		MaxRetries:       redisConfig.MaxRetries,
+		MinRetryBackoff:  time.Duration(redisConfig.MinRetryBackoffMs) * time.Millisecond,
+		MaxRetryBackoff:  time.Duration(redisConfig.MaxRetryBackoffMs) * time.Millisecond,
+		// Bound commands by the deadline of their context, like the request they serve
+		ContextTimeoutEnabled: true,
+	}
 
-// LoadRedisConfig loads the Redis configuration from a TOML file
-func LoadRedisConfig(filePath string) (*Config, error) {
-	var config Config
+	if redisConfig.TLS {
+		var err error
+		options.TLSConfig, err = tlsConfig(redisConfig)
+		if err != nil {
+			return nil, fmt.Errorf("loading TLS configuration: %w", err)
+		}
+	}
 
-	// Read the TOML file
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return &config, err
+	var client redis.UniversalClient
+	switch redisConfig.Mode {
+	case config.RedisCluster:
+		client = redis.NewClusterClient(options.Cluster())
+	case config.RedisSentinel:
+		client = redis.NewFailoverClient(options.Failover())
+	default:
+		options.Addrs = []string{redisConfig.Addr}
+		client = redis.NewClient(options.Simple())
 	}
+	client.AddHook(errorHook{})
 
-	// Unmarshal the TOML data into the Config struct
-	err = toml.Unmarshal(data, &config)
-	if err != nil {
-		return &config, err
+	// Check if the connection to Redis is successful
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("connecting to %s Redis at %v: %w", redisConfig.Mode, options.Addrs, err)
 	}
 
-	return &config, nil
+	slog.Info("Connected to Redis", "mode", redisConfig.Mode, "addrs", options.Addrs)
+	return &Store{client: client}, nil
 }
 
-// ConnectRedis creates a Redis connection pool based on the provided configuration
-func ConnectRedis() *redis.Client {
+// Close closes the connections to Redis.
+func (s *Store) Close() error {
+	s.results.close()
+	return s.client.Close()
+}
 
-	once.Do(func() {
-		configPath, er := utils.GetFilePath("config", "redis.toml")
-		if er != nil {
-			panic(er)
-		}
+// tlsConfig returns the TLS configuration of the connections to Redis,
+// trusting the given CA and presenting the client certificate if they are configured.
+func tlsConfig(redisConfig config.RedisConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         redisConfig.TLSServerName,
+		InsecureSkipVerify: redisConfig.TLSInsecureSkipVerify,
+	}
 
-		ConfigSingle, err = LoadRedisConfig(configPath)
+	if redisConfig.TLSCAFile != "" {
+		pem, err := os.ReadFile(redisConfig.TLSCAFile)
 		if err != nil {
-			log.Fatalf("Error loading Redis config: %v", err)
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", redisConfig.TLSCAFile)
 		}
+	}
 
-		// Create a clientPool of Redis connections
-		poolSize := 10
-		minIdleConns := 5
-
-		// Create a new Redis Options struct
-		options := &redis.Options{
-			Addr:         ConfigSingle.Redis.Addr,
-			Password:     ConfigSingle.Redis.Password,
-			DB:           ConfigSingle.Redis.DB,
-			PoolSize:     poolSize,
-			MinIdleConns: minIdleConns,
+	if redisConfig.TLSCertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(redisConfig.TLSCertFile, redisConfig.TLSKeyFile)
+		if err != nil {
+			return nil, err
 		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// jobScopedKey returns the key of a job's data under prefix.
+// The job key is a hash tag, so in a cluster all keys of a job are in the same slot
+// and can be updated together in one transaction.
+func jobScopedKey(prefix, jobKey string) string {
+	return prefix + ":{" + jobKey + "}"
+}
+
+// Ping checks that Redis answers.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
 
-		clientPool = redis.NewClient(options)
+// A queue holds its jobs in one list per language, so jobs of a language without capacity stay in Redis
+// without blocking the others. The lists and the set of their languages share the queue's hash tag,
+// so in a cluster a single BRPOP can wait on all of them.
 
-		// Check if the connection to Redis is successful
-		_, err = clientPool.Ping(context.Background()).Result()
+// languageQueueKey is the list of a queue holding the jobs of a language.
+func languageQueueKey(queueName, language string) string {
+	return "{" + queueName + "}:" + language
+}
+
+// queueLanguagesKey is the set of the languages a queue has held jobs of.
+func queueLanguagesKey(queueName string) string {
+	return "{" + queueName + "}:languages"
+}
+
+// pushJobs queues the commands adding jobs to the queue, at its tail or, to be taken next, at its head.
+func pushJobs(ctx context.Context, pipe redis.Pipeliner, queueName string, head bool, jobs ...models.Job) error {
+	for _, job := range jobs {
+		item, err := json.Marshal(job)
 		if err != nil {
-			log.Fatalf("Failed to connect to Redis: %v\n", err)
+			return err
+		}
+		pipe.SAdd(ctx, queueLanguagesKey(queueName), job.Language)
+		if head {
+			pipe.RPush(ctx, languageQueueKey(queueName, job.Language), item)
 		} else {
-			log.Println("Connected to Redis")
+			pipe.LPush(ctx, languageQueueKey(queueName, job.Language), item)
 		}
+	}
+	return nil
+}
+
+// EnqueueItem adds a job at the tail of the queue of its language.
+func (s *Store) EnqueueItem(ctx context.Context, queueName string, codeSubmission models.Job) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		return pushJobs(ctx, pipe, queueName, false, codeSubmission)
 	})
+	return err
+}
 
-	return clientPool
+// RequeueItem puts back a job taken off the queue but not run, at the head of the queue of its language.
+func (s *Store) RequeueItem(ctx context.Context, queueName string, job models.Job) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		return pushJobs(ctx, pipe, queueName, true, job)
+	})
+	return err
 }
 
-func EnqueueItem(queueName string, codeSubmission models.Job) error {
-	// Convert the codeSubmission struct to a JSON string
-	result, err := json.Marshal(codeSubmission)
-	if err != nil {
-		return err
-	}
+// QueueLanguages returns the languages a queue has held jobs of.
+func (s *Store) QueueLanguages(ctx context.Context, queueName string) ([]string, error) {
+	return s.client.SMembers(ctx, queueLanguagesKey(queueName)).Result()
+}
 
-	// Enqueue the JSON string in the Redis list
-	return clientPool.LPush(context.Background(), queueName, result).Err()
+// DequeueItem waits for a job of any language in a queue until ctx is done.
+func (s *Store) DequeueItem(ctx context.Context, queueName string) (models.Job, error) {
+	for {
+		languages, err := s.QueueLanguages(ctx, queueName)
+		if err != nil {
+			return models.Job{}, err
+		}
+
+		job, found, err := s.DequeueLanguages(ctx, queueName, languages)
+		if err != nil || found {
+			return job, err
+		}
+		if ctx.Err() != nil {
+			return models.Job{}, ctx.Err()
+		}
+	}
 }
 
-func DequeueItem(queueName string) (models.Job, error) {
-	// Dequeue the JSON string from the Redis list
-	result, err := clientPool.BRPop(context.Background(), 0, queueName).Result()
-	if err != nil {
-		return models.Job{}, err
+// DequeueLanguages takes the next job of one of the given languages off a queue, preferring the languages
+// listed first. It waits for a job for a bounded time only, so the caller can change the languages it takes,
+// and reports whether there was one.
+func (s *Store) DequeueLanguages(ctx context.Context, queueName string, languages []string) (models.Job, bool, error) {
+	if len(languages) == 0 {
+		select {
+		case <-time.After(dequeueBlock):
+		case <-ctx.Done():
+		}
+		return models.Job{}, false, nil
 	}
 
-	value := result[1]
+	keys := make([]string, len(languages))
+	for i, language := range languages {
+		keys[i] = languageQueueKey(queueName, language)
+	}
 
-	// Convert the JSON string back to the codeSubmission struct
-	var codeSubmission models.Job
-	err = json.Unmarshal([]byte(value), &codeSubmission)
-	if err != nil {
-		return models.Job{}, err
+	// Block for a bounded time so a cancelled ctx is noticed
+	result, err := s.client.BRPop(ctx, dequeueBlock, keys...).Result()
+	if errors.Is(err, redis.Nil) {
+		return models.Job{}, false, nil
+	} else if err != nil {
+		return models.Job{}, false, err
 	}
 
-	return codeSubmission, nil
+	var job models.Job
+	if err := json.Unmarshal([]byte(result[1]), &job); err != nil {
+		return models.Job{}, false, err
+	}
+	return job, true, nil
 }
 
-func SetCache(key string, data interface{}, expiration time.Duration) error {
+func (s *Store) SetCache(ctx context.Context, key string, data interface{}, expiration time.Duration) error {
 	// Convert the data to a JSON string
 	result, err := json.MarshalIndent(data, "", " ")
 	if err != nil {
@@ -135,15 +236,15 @@ func SetCache(key string, data interface{}, expiration time.Duration) error {
 	}
 
 	// Set the JSON string in Redis with expiration time
-	return clientPool.Set(context.Background(), key, result, expiration).Err()
+	return s.client.Set(ctx, key, result, expiration).Err()
 }
 
-func GetCache(key string) (models.CompilationResult, error) {
+func (s *Store) GetCache(ctx context.Context, key string) (models.CompilationResult, error) {
 	// Check if the key exists in the cache
-	result, err := clientPool.Get(context.Background(), key).Result()
+	result, err := s.client.Get(ctx, key).Result()
 	if err == redis.Nil {
 		// Key does not exist in the cache
-		return models.CompilationResult{}, fmt.Errorf("key not found in cache")
+		return models.CompilationResult{}, fmt.Errorf("key not found in cache: %w", err)
 	} else if err != nil {
 		// Error occurred while fetching from the cache
 		return models.CompilationResult{}, err
@@ -2,50 +2,39 @@ package redis
 
 import (
 	"CodeXecutor/models"
+	"CodeXecutor/pkg/config"
 	"context"
-	"os"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
-const testConfigFilePath = "test-redis-config.toml"
-
-func TestLoadRedisConfig(t *testing.T) {
-	// Create a sample Redis configuration file for testing
-	testConfigContent := `
-		[redis]
-		Addr = "localhost:6379"
-		Password = ""
-		DB = 0
-		PoolSize = 10
-		MinIdleConns = 5
-	`
-
-	err := os.WriteFile(testConfigFilePath, []byte(testConfigContent), 0644)
-	assert.NoError(t, err, "Error creating test Redis config file")
-	defer os.Remove(testConfigFilePath)
-
-	config, err := LoadRedisConfig(testConfigFilePath)
-	assert.NoError(t, err, "Error loading Redis config")
-	assert.NotNil(t, config, "Config should not be nil")
-	assert.Equal(t, "localhost:6379", config.Redis.Addr, "Incorrect Redis address in config")
-
+// newTestStore connects to the Redis server of the default configuration for a test.
+func newTestStore(t *testing.T) *Store {
+	store, err := NewStore(context.Background(), config.Default().Redis)
+	if !assert.NoError(t, err, "Error connecting to Redis") {
+		t.FailNow()
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
 }
 
-func TestConnectRedis(t *testing.T) {
-	// Ensure that ConnectRedis initializes the Redis client and connects to Redis
-	client := ConnectRedis()
-	assert.NotNil(t, client, "Redis client should not be nil")
-
-	// Check if the client can perform a basic operation (Ping)
-	pong, err := client.Ping(context.Background()).Result()
-	assert.NoError(t, err, "Failed to ping Redis")
-	assert.Equal(t, "PONG", pong, "Unexpected ping response")
+func TestNewStore(t *testing.T) {
+	// Ensure that NewStore connects to Redis
+	store := newTestStore(t)
+	assert.NoError(t, store.Ping(context.Background()), "Failed to ping Redis")
+
+	// A server that cannot be reached is reported rather than ending the process
+	redisConfig := config.Default().Redis
+	redisConfig.Addr = "localhost:1"
+	redisConfig.MaxRetries = -1
+	_, err := NewStore(context.Background(), redisConfig)
+	assert.ErrorContains(t, err, "localhost:1", "Connecting to an unreachable server should fail")
 }
 
 func TestEnqueueDequeueItem(t *testing.T) {
+	store := newTestStore(t)
 
 	// Create a sample job for testing
 	job := models.Job{
@@ -56,26 +45,66 @@ func TestEnqueueDequeueItem(t *testing.T) {
 	}
 
 	// Enqueue the job
-	err := EnqueueItem("test-queue", job)
+	err := store.EnqueueItem(context.Background(), "test-queue", job)
 	assert.NoError(t, err, "Error enqueuing item")
 
 	// Dequeue the job
-	dequeuedJob, err := DequeueItem("test-queue")
+	dequeuedJob, err := store.DequeueItem(context.Background(), "test-queue")
 	assert.NoError(t, err, "Error dequeuing item")
 	assert.Equal(t, job, dequeuedJob, "Enqueued and dequeued jobs should be equal")
 
 }
 
+func TestDequeueLanguages(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	queue := "test-language-queue"
+	store.client.Del(ctx, queueLanguagesKey(queue), languageQueueKey(queue, "java"), languageQueueKey(queue, "python"))
+
+	java := models.Job{ID: "java-1", Language: "java", Tenant: "test"}
+	python1 := models.Job{ID: "python-1", Language: "python", Tenant: "test"}
+	python2 := models.Job{ID: "python-2", Language: "python", Tenant: "test"}
+	for _, job := range []models.Job{java, python1, python2} {
+		assert.NoError(t, store.EnqueueItem(ctx, queue, job), "Error enqueuing item")
+	}
+
+	length, err := store.QueueLength(ctx, queue)
+	assert.NoError(t, err, "Error getting queue length")
+	assert.EqualValues(t, 3, length, "The queue length should count all languages")
+
+	// Jobs of languages not taken stay queued
+	job, found, err := store.DequeueLanguages(ctx, queue, []string{"python"})
+	assert.NoError(t, err, "Error dequeuing item")
+	assert.True(t, found, "A python job should be dequeued")
+	assert.Equal(t, python1, job, "Jobs of a language should be dequeued in order")
+
+	// A job put back is taken next
+	assert.NoError(t, store.RequeueItem(ctx, queue, job), "Error requeuing item")
+	job, _, _ = store.DequeueLanguages(ctx, queue, []string{"python"})
+	assert.Equal(t, python1, job, "A requeued job should be dequeued first")
+
+	job, _, _ = store.DequeueLanguages(ctx, queue, []string{"python"})
+	assert.Equal(t, python2, job, "The second python job should follow")
+
+	_, found, err = store.DequeueLanguages(ctx, queue, []string{"python"})
+	assert.NoError(t, err, "An empty queue should not be an error")
+	assert.False(t, found, "No python job should be left")
+
+	length, _ = store.QueueLength(ctx, queue)
+	assert.EqualValues(t, 1, length, "The java job should still be queued")
+}
+
 func TestSetGetCache(t *testing.T) {
+	store := newTestStore(t)
 
 	// Set data in cache
 	key := "unique-key-2"
 	data := models.CompilationResult{ExitCode: 0, Output: ".", Error: nil}
-	err := SetCache(key, data, time.Minute)
+	err := store.SetCache(context.Background(), key, data, time.Minute)
 	assert.NoError(t, err, "Error setting data in cache")
 
 	// Get data from cache
-	cachedData, err := GetCache(key)
+	cachedData, err := store.GetCache(context.Background(), key)
 	assert.NoError(t, err, "Error getting data from cache")
 	assert.NotEmpty(t, cachedData, "Cached data should not be empty")
 
@@ -85,30 +114,28 @@ func TestSetGetCache(t *testing.T) {
 }
 
 func TestSetCacheExpiredGetCache(t *testing.T) {
-	client := ConnectRedis()
-	defer client.Close()
+	store := newTestStore(t)
 
 	key := "expiredKey"
 	data := "testData"
 	expiration := time.Millisecond * 100 // Very short expiration time for testing
 
 	// Test SetCache with short expiration time
-	err := SetCache(key, data, expiration)
+	err := store.SetCache(context.Background(), key, data, expiration)
 	assert.NoError(t, err, "SetCache should not return an error")
 
 	// Wait for expiration
 	time.Sleep(expiration + time.Millisecond*50)
 
 	// Test GetCache for an expired key
-	_, err = GetCache(key)
+	_, err = store.GetCache(context.Background(), key)
 	assert.Error(t, err, "GetCache should return an error for an expired key")
 }
 
 func TestGetCacheKeyNotFound(t *testing.T) {
-	client := ConnectRedis()
-	defer client.Close()
+	store := newTestStore(t)
 
 	// Test GetCache for a non-existent key
-	_, err := GetCache("nonExistentKey")
+	_, err := store.GetCache(context.Background(), "nonExistentKey")
 	assert.Error(t, err, "GetCache should return an error for a non-existent key")
 }
@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// resultPattern matches the channels announcing the results of all jobs, see resultChannel.
+const resultPattern = "results:*"
+
+// resultWaiters shares one pattern subscription to the results of all jobs between the requests waiting
+// for results in this process, so a waiting request does not hold a Redis connection of its own.
+// The subscription is made by the first wait and ends when the store is closed.
+type resultWaiters struct {
+	mu      sync.Mutex
+	pubsub  *redis.PubSub
+	waiters map[string][]chan struct{}
+}
+
+// wait registers a waiter for the result of a job. The returned channel is closed once the result
+// is published and stop unregisters the waiter. Results published after wait returns are not missed.
+func (rw *resultWaiters) wait(ctx context.Context, client redis.UniversalClient, jobKey string) (<-chan struct{}, func(), error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.pubsub == nil {
+		pubsub := client.PSubscribe(ctx, resultPattern)
+		if _, err := pubsub.Receive(ctx); err != nil {
+			pubsub.Close()
+			return nil, nil, err
+		}
+		rw.pubsub = pubsub
+		rw.waiters = map[string][]chan struct{}{}
+		go rw.dispatch(pubsub.Channel())
+	}
+
+	published := make(chan struct{})
+	rw.waiters[jobKey] = append(rw.waiters[jobKey], published)
+
+	stop := func() {
+		rw.mu.Lock()
+		defer rw.mu.Unlock()
+		rw.remove(jobKey, published)
+	}
+	return published, stop, nil
+}
+
+// dispatch wakes the waiters of every published result until the subscription is closed.
+func (rw *resultWaiters) dispatch(messages <-chan *redis.Message) {
+	for message := range messages {
+		jobKey := strings.TrimPrefix(message.Channel, resultChannel(""))
+
+		rw.mu.Lock()
+		for _, published := range rw.waiters[jobKey] {
+			close(published)
+		}
+		delete(rw.waiters, jobKey)
+		rw.mu.Unlock()
+	}
+}
+
+// remove unregisters a waiter that is still waiting. The caller must hold rw.mu.
+func (rw *resultWaiters) remove(jobKey string, published chan struct{}) {
+	waiters := rw.waiters[jobKey]
+	for i, waiter := range waiters {
+		if waiter == published {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+
+	if len(waiters) == 0 {
+		delete(rw.waiters, jobKey)
+	} else {
+		rw.waiters[jobKey] = waiters
+	}
+}
+
+// close ends the subscription. Waiting requests are no longer woken and stop at their timeout.
+func (rw *resultWaiters) close() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.pubsub == nil {
+		return nil
+	}
+	err := rw.pubsub.Close()
+	rw.pubsub = nil
+	return err
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// deliveriesKey is the sorted set of pending webhook deliveries, scored by when they are due.
+const deliveriesKey = "webhook-deliveries"
+
+func deliveryLogKey(jobKey string) string {
+	return jobScopedKey("webhook-log", jobKey)
+}
+
+// ScheduleDelivery schedules a webhook delivery to be attempted at the given time.
+func (s *Store) ScheduleDelivery(ctx context.Context, delivery models.Delivery, at time.Time) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	return s.client.ZAdd(ctx, deliveriesKey, redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: data,
+	}).Err()
+}
+
+// ClaimDueDeliveries removes up to limit deliveries that are due and returns them.
+// A delivery is returned to a single caller only, even with dispatchers in several processes.
+func (s *Store) ClaimDueDeliveries(ctx context.Context, now time.Time, limit int64) ([]models.Delivery, error) {
+
+	members, err := s.client.ZRangeByScore(ctx, deliveriesKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: limit,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []models.Delivery
+	for _, member := range members {
+		// Another dispatcher may have claimed the delivery in the meantime
+		removed, err := s.client.ZRem(ctx, deliveriesKey, member).Result()
+		if err != nil {
+			return deliveries, err
+		}
+		if removed == 0 {
+			continue
+		}
+
+		var delivery models.Delivery
+		if err := json.Unmarshal([]byte(member), &delivery); err != nil {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+// LogDeliveryAttempt appends an attempt to the delivery log of a job.
+func (s *Store) LogDeliveryAttempt(ctx context.Context, jobKey string, attempt models.DeliveryAttempt) error {
+	data, err := json.Marshal(attempt)
+	if err != nil {
+		return err
+	}
+
+	key := deliveryLogKey(jobKey)
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, data)
+		pipe.Expire(ctx, key, StatusExpiration)
+		return nil
+	})
+	return err
+}
+
+// GetDeliveryLog returns the webhook delivery attempts of a job, oldest first.
+func (s *Store) GetDeliveryLog(ctx context.Context, jobKey string) ([]models.DeliveryAttempt, error) {
+	items, err := s.client.LRange(ctx, deliveryLogKey(jobKey), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := make([]models.DeliveryAttempt, 0, len(items))
+	for _, item := range items {
+		var attempt models.DeliveryAttempt
+		if err := json.Unmarshal([]byte(item), &attempt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, nil
+}
@@ -0,0 +1,105 @@
+package security
+
+import (
+	appConfig "CodeXecutor/pkg/config"
+	redisClient "CodeXecutor/pkg/redis"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUnknownAPIKey is returned for API keys that belong to no tenant.
+var ErrUnknownAPIKey = errors.New("unknown API key")
+
+// tenantPattern restricts tenant names to characters that cannot be mistaken for the end of the tenant in a job key,
+// see models.JobKey.
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+type AuthConfig struct {
+	Admins []string          `toml:"admins"` // Tenants allowed to read the jobs of every tenant
+	Keys   map[string]string `toml:"keys"`   // Tenants by the hex SHA-256 hash of their API keys
+}
+
+func init() {
+	appConfig.Register("auth.toml", func() appConfig.Validator { return &AuthConfig{} })
+}
+
+// getAuthConfig returns the API keys of the config file, loaded and validated with the configuration file.
+func getAuthConfig() *AuthConfig {
+	return appConfig.Registered[*AuthConfig]("auth.toml")
+}
+
+// Validate checks that the keys are SHA-256 hashes belonging to a valid tenant, and that the admins are valid tenants.
+func (c *AuthConfig) Validate() error {
+	var errs []error
+	for hash, tenant := range c.Keys {
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			errs = append(errs, fmt.Errorf("keys: %q is not a hex SHA-256 hash", hash))
+		}
+		if err := ValidateTenant(tenant); err != nil {
+			errs = append(errs, fmt.Errorf("keys.%s: %w", hash, err))
+		}
+	}
+	for _, admin := range c.Admins {
+		if err := ValidateTenant(admin); err != nil {
+			errs = append(errs, fmt.Errorf("admins: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateTenant checks that a tenant name is safe to scope the keys of its jobs with.
+func ValidateTenant(tenant string) error {
+	if !tenantPattern.MatchString(tenant) {
+		return fmt.Errorf("invalid tenant %q: only letters, digits, '.', '_' and '-' are allowed", tenant)
+	}
+	return nil
+}
+
+// HashAPIKey returns the hex SHA-256 hash under which an API key is stored.
+// Only hashes are stored, so the keys cannot be read from the config file or Redis.
+func HashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// LookupTenant returns the tenant an API key belongs to.
+// Keys in the config file take precedence over keys stored in Redis.
+func LookupTenant(ctx context.Context, store *redisClient.Store, apiKey string) (string, error) {
+	if apiKey == "" {
+		return "", ErrUnknownAPIKey
+	}
+
+	hash := HashAPIKey(apiKey)
+	if tenant, ok := getAuthConfig().Keys[hash]; ok {
+		return tenant, nil
+	}
+
+	tenant, err := store.GetAPIKeyTenant(ctx, hash)
+	if err == redis.Nil {
+		return "", ErrUnknownAPIKey
+	} else if err != nil {
+		return "", err
+	}
+
+	// Keys stored in Redis are not validated with the config file
+	if err := ValidateTenant(tenant); err != nil {
+		return "", fmt.Errorf("API key stored in Redis: %w", err)
+	}
+	return tenant, nil
+}
+
+// IsAdmin reports whether a tenant may read the jobs of other tenants.
+func IsAdmin(tenant string) bool {
+	for _, admin := range getAuthConfig().Admins {
+		if admin == tenant {
+			return true
+		}
+	}
+	return false
+}
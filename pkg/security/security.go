@@ -1 +1,63 @@
 package security
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	MaxFiles          = 100      // Maximum number of files in a submitted file tree
+	MaxFileTreeSize   = 1 << 20  // Maximum total size of a submitted file tree in bytes
+	MaxFilePathLength = 255      // Maximum length of a file path
+	MaxCodeSize       = 64 << 10 // Maximum size of code submitted without a file tree in bytes
+	MaxStdinSize      = 64 << 10 // Maximum size of the input of a job in bytes
+	MinMemory         = 6 << 20  // Smallest memory limit Docker accepts for a container in bytes
+)
+
+// filePathPattern restricts file paths to characters that are safe to pass to a shell.
+var filePathPattern = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+
+// ValidateFileTree checks a submitted file tree against the size and file-count limits,
+// and makes sure every path stays inside the working directory and the entry point is part of the tree.
+func ValidateFileTree(files map[string]string, entrypoint string) error {
+	if len(files) > MaxFiles {
+		return fmt.Errorf("too many files: %d, at most %d are allowed", len(files), MaxFiles)
+	}
+
+	size := 0
+	for name, content := range files {
+		if err := ValidateFilePath(name); err != nil {
+			return err
+		}
+		size += len(content)
+	}
+
+	if size > MaxFileTreeSize {
+		return fmt.Errorf("files too large: %d bytes, at most %d are allowed", size, MaxFileTreeSize)
+	}
+
+	if _, ok := files[entrypoint]; !ok {
+		return fmt.Errorf("entry point %q is not one of the files", entrypoint)
+	}
+
+	return nil
+}
+
+// ValidateFilePath checks that a file path is relative, clean and stays inside the working directory.
+func ValidateFilePath(name string) error {
+	if len(name) > MaxFilePathLength {
+		return fmt.Errorf("file path too long: %q", name)
+	}
+
+	if !filePathPattern.MatchString(name) {
+		return fmt.Errorf("invalid file path %q: only letters, digits, '.', '_', '-' and '/' are allowed", name)
+	}
+
+	if path.IsAbs(name) || path.Clean(name) != name || name == "." || strings.HasPrefix(name, "../") || name == ".." {
+		return fmt.Errorf("invalid file path %q: must be a clean relative path", name)
+	}
+
+	return nil
+}
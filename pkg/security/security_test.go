@@ -0,0 +1,46 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFileTree(t *testing.T) {
+	files := map[string]string{
+		"main.py":         "import util",
+		"lib/util.py":     "x = 1",
+		"lib/__init__.py": "",
+	}
+	assert.NoError(t, ValidateFileTree(files, "main.py"), "Valid file tree should be accepted")
+	assert.Error(t, ValidateFileTree(files, "missing.py"), "Entry point must be one of the files")
+
+	assert.Error(t, ValidateFileTree(map[string]string{"main.py": strings.Repeat("x", MaxFileTreeSize+1)}, "main.py"), "Oversized file tree should be rejected")
+
+	many := map[string]string{}
+	for i := 0; i <= MaxFiles; i++ {
+		many[strings.Repeat("a", i+1)] = ""
+	}
+	assert.Error(t, ValidateFileTree(many, "a"), "File tree with too many files should be rejected")
+}
+
+func TestValidateFilePath(t *testing.T) {
+	assert.NoError(t, ValidateFilePath("src/main.go"), "Nested relative path should be accepted")
+
+	for _, name := range []string{"/etc/passwd", "../main.go", "src/../../main.go", "./main.go", "src//main.go", "main go", "main.go;rm", ".", ""} {
+		assert.Error(t, ValidateFilePath(name), "Path %q should be rejected", name)
+	}
+}
+
+func TestAuthConfigValidate(t *testing.T) {
+	hash := HashAPIKey("key")
+	assert.NoError(t, (&AuthConfig{Admins: []string{"ops"}, Keys: map[string]string{hash: "acme-1.eu_west"}}).Validate(), "Valid tenants should be accepted")
+
+	// A tenant "a:b" would read the jobs of tenant "a" asking for IDs starting with "b:"
+	config := &AuthConfig{Admins: []string{"ops:all"}, Keys: map[string]string{hash: "a:b", HashAPIKey("other"): ""}}
+	err := config.Validate()
+	assert.ErrorContains(t, err, `invalid tenant "a:b"`, "Tenants containing ':' should be rejected")
+	assert.ErrorContains(t, err, `invalid tenant ""`, "Keys without a tenant should be rejected")
+	assert.ErrorContains(t, err, `admins: invalid tenant "ops:all"`, "Invalid admins should be rejected")
+}
@@ -0,0 +1,131 @@
+package tracing
+
+import (
+	"CodeXecutor/models"
+	appConfig "CodeXecutor/pkg/config"
+	"CodeXecutor/pkg/logging"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans created by the service.
+const tracerName = "CodeXecutor"
+
+type TracingConfig struct {
+	Enabled     bool    `toml:"enabled"`      // Whether spans are exported
+	Endpoint    string  `toml:"endpoint"`     // host:port of the OTLP/HTTP collector
+	Insecure    bool    `toml:"insecure"`     // Export over plain HTTP instead of HTTPS
+	ServiceName string  `toml:"service_name"` // Name of the service in the traces
+	SampleRatio float64 `toml:"sample_ratio"` // Share of new traces recorded, between 0 and 1
+}
+
+type Config struct {
+	Tracing TracingConfig `toml:"tracing"`
+}
+
+func init() {
+	appConfig.Register("tracing.toml", func() appConfig.Validator {
+		return &Config{Tracing: TracingConfig{Endpoint: "localhost:4318", Insecure: true, ServiceName: "codexecutor", SampleRatio: 1}}
+	})
+}
+
+// GetConfig returns the tracing configuration, loaded and validated with the configuration file.
+func GetConfig() *Config {
+	return appConfig.Registered[*Config]("tracing.toml")
+}
+
+// Validate checks that the settings are usable, reporting every invalid one.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio: must be between 0 and 1"))
+	}
+	if c.Tracing.Enabled {
+		if _, _, err := net.SplitHostPort(c.Tracing.Endpoint); err != nil {
+			errs = append(errs, fmt.Errorf("tracing.endpoint: must be host:port: %v", err))
+		}
+		if c.Tracing.ServiceName == "" {
+			errs = append(errs, errors.New("tracing.service_name: is required"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Setup installs the W3C trace context propagator and, if tracing is enabled, a tracer provider
+// exporting spans to the configured OTLP collector. The returned function flushes the pending spans.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	config := GetConfig().Tracing
+	if !config.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(config.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span of the service, a child of the span in ctx if there is one.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// End ends a span, marking it as failed if err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject stores the trace context of ctx in a job, so the spans of the worker running it join the trace.
+func Inject(ctx context.Context, job *models.Job) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	job.TraceContext = nil
+	if len(carrier) > 0 {
+		job.TraceContext = carrier
+	}
+}
+
+// Extract returns a copy of ctx holding the trace context carried by a job.
+func Extract(ctx context.Context, job models.Job) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(job.TraceContext))
+}
+
+// WithTraceID returns a copy of ctx whose logger logs the trace ID of the span in ctx, if it is sampled.
+func WithTraceID(ctx context.Context) context.Context {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsSampled() {
+		return ctx
+	}
+	return logging.WithLogger(ctx, logging.FromContext(ctx).With("trace_id", spanContext.TraceID().String()))
+}
@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInjectExtract(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3},
+		SpanID:     trace.SpanID{4, 5, 6},
+		TraceFlags: trace.FlagsSampled,
+	})
+	job := models.Job{ID: "abc"}
+	Inject(trace.ContextWithSpanContext(context.Background(), spanContext), &job)
+	assert.Contains(t, job.TraceContext, "traceparent", "The trace context should be stored in the job")
+
+	// The trace context travels through the queue with the serialized job
+	data, err := json.Marshal(job)
+	assert.NoError(t, err, "Error marshalling job")
+	var dequeued models.Job
+	assert.NoError(t, json.Unmarshal(data, &dequeued), "Error unmarshalling job")
+
+	extracted := trace.SpanContextFromContext(Extract(context.Background(), dequeued))
+	assert.Equal(t, spanContext.TraceID(), extracted.TraceID())
+	assert.Equal(t, spanContext.SpanID(), extracted.SpanID())
+	assert.True(t, extracted.IsRemote(), "The extracted span should be remote")
+
+	Inject(context.Background(), &job)
+	assert.Nil(t, job.TraceContext, "Jobs submitted outside a trace should carry no trace context")
+}
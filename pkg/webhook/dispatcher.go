@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"CodeXecutor/models"
+	"CodeXecutor/pkg/metrics"
+	redisClient "CodeXecutor/pkg/redis"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// pollInterval is how often the dispatcher looks for due deliveries.
+const pollInterval = time.Second
+
+// maxInFlight bounds the deliveries made at once, so a slow endpoint only holds up its own.
+const maxInFlight = 10
+
+// Enqueue schedules the delivery of a job's result to its callback URL.
+func Enqueue(ctx context.Context, store *redisClient.Store, job models.Job, result models.CompilationResult) error {
+	return store.ScheduleDelivery(ctx, models.Delivery{
+		JobID:       job.ID,
+		Tenant:      job.Tenant,
+		CallbackURL: job.CallbackURL,
+		Result:      result,
+		Attempt:     1,
+		RequestID:   job.RequestID,
+	}, time.Now())
+}
+
+// RunDispatcher delivers due webhooks until ctx is done, then waits for the deliveries in flight.
+// Failed deliveries are retried with exponential backoff, every attempt is recorded in the job's delivery log.
+func RunDispatcher(ctx context.Context, store *redisClient.Store) {
+	metrics.RegisterQueue("webhook-deliveries", func() (int64, error) { return store.PendingDeliveries(ctx) })
+
+	config := GetConfig()
+	httpClient := newHTTPClient(config)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	slots := make(chan struct{}, maxInFlight)
+	var inFlight sync.WaitGroup
+	defer inFlight.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Only this loop takes slots, so as many deliveries as are claimed can start right away
+			free := maxInFlight - len(slots)
+			if free == 0 {
+				continue
+			}
+
+			deliveries, err := store.ClaimDueDeliveries(ctx, time.Now(), int64(free))
+			if err != nil {
+				slog.Error("Error claiming webhook deliveries", "error", err)
+			}
+
+			for _, delivery := range deliveries {
+				slots <- struct{}{}
+				inFlight.Add(1)
+				go func(delivery models.Delivery) {
+					defer inFlight.Done()
+					defer func() { <-slots }()
+					deliver(ctx, store, httpClient, config, delivery)
+				}(delivery)
+			}
+		}
+	}
+}
+
+// newHTTPClient returns the client making the deliveries.
+func newHTTPClient(config WebhookConfig) *http.Client {
+	return &http.Client{
+		Timeout: time.Duration(config.TimeoutSeconds) * time.Second,
+		// A redirect could lead the signed delivery to a host that is not allowed, so the callback's answer is taken as is
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+// deliver makes one attempt to deliver a webhook and schedules a retry if it fails.
+func deliver(ctx context.Context, store *redisClient.Store, httpClient *http.Client, config WebhookConfig, delivery models.Delivery) {
+	logger := slog.With("job_id", delivery.JobID, "tenant", delivery.Tenant, "request_id", delivery.RequestID, "attempt", delivery.Attempt)
+	attempt := models.DeliveryAttempt{Attempt: delivery.Attempt, Time: time.Now().Unix()}
+
+	statusCode, err := post(ctx, httpClient, config.Secret, delivery)
+	attempt.StatusCode = statusCode
+	if err != nil {
+		attempt.Error = err.Error()
+	} else {
+		attempt.Delivered = true
+	}
+
+	// A delivery interrupted by shutdown is still logged and retried
+	ctx = context.WithoutCancel(ctx)
+	if err := store.LogDeliveryAttempt(ctx, delivery.JobKey(), attempt); err != nil {
+		logger.Error("Error logging webhook delivery", "error", err)
+	}
+
+	if attempt.Delivered {
+		logger.Info("Delivered webhook", "status_code", attempt.StatusCode)
+		return
+	}
+
+	if delivery.Attempt >= config.MaxAttempts {
+		logger.Warn("Giving up webhook delivery", "error", attempt.Error)
+		return
+	}
+
+	backoff := time.Duration(config.RetryBackoffSeconds) * time.Second << (delivery.Attempt - 1)
+	delivery.Attempt++
+	if err := store.ScheduleDelivery(ctx, delivery, time.Now().Add(backoff)); err != nil {
+		logger.Error("Error scheduling webhook retry", "error", err)
+	}
+}
+
+// post sends the result of a job to its callback URL, signed with the secret.
+func post(ctx context.Context, httpClient *http.Client, secret string, delivery models.Delivery) (int, error) {
+	body, err := json.Marshal(payload(delivery))
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+	req.Header.Set("X-CodeXecutor-Delivery-Attempt", strconv.Itoa(delivery.Attempt))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// payload is the body posted to the callback, the result rendered like the data of /result.
+func payload(delivery models.Delivery) map[string]interface{} {
+	var errorMessage interface{}
+	if delivery.Result.Error != nil {
+		errorMessage = delivery.Result.Error.Error()
+	}
+
+	return map[string]interface{}{
+		"submissionid": delivery.JobID,
+		"status":       delivery.Result.Status,
+		"output":       delivery.Result.Output,
+		"error":        errorMessage,
+		"exitcode":     delivery.Result.ExitCode,
+	}
+}
@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"CodeXecutor/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostRefusesRedirects(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("The delivery should not follow the redirect")
+	}))
+	defer internal.Close()
+
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get(TimestampHeader), "The delivery should carry the timestamp it was signed at")
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer callback.Close()
+
+	httpClient := newHTTPClient(WebhookConfig{TimeoutSeconds: 5})
+	statusCode, err := post(context.Background(), httpClient, "secret", models.Delivery{JobID: "job", CallbackURL: callback.URL, Attempt: 1})
+
+	assert.Equal(t, http.StatusFound, statusCode, "The redirect should be recorded as the callback's answer")
+	assert.EqualError(t, err, "callback responded with status 302", "A redirect should fail the attempt")
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	appConfig "CodeXecutor/pkg/config"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of the timestamp and the delivered body.
+	SignatureHeader = "X-CodeXecutor-Signature"
+	// TimestampHeader carries the Unix time the delivery was signed at, so receivers can reject replays.
+	TimestampHeader = "X-CodeXecutor-Timestamp"
+)
+
+type WebhookConfig struct {
+	Secret              string   `toml:"secret"`                // Key signing the delivered bodies
+	AllowedHosts        []string `toml:"allowed_hosts"`         // Hosts callbacks may be sent to, "*.example.com" matches subdomains
+	MaxAttempts         int      `toml:"max_attempts"`          // Attempts before a delivery is given up
+	RetryBackoffSeconds int      `toml:"retry_backoff_seconds"` // Delay before the first retry, doubled for every further retry
+	TimeoutSeconds      int      `toml:"timeout_seconds"`       // Timeout of a single delivery attempt
+}
+
+type Config struct {
+	Webhook WebhookConfig `toml:"webhook"`
+}
+
+func init() {
+	appConfig.Register("webhook.toml", func() appConfig.Validator {
+		return &Config{Webhook: WebhookConfig{MaxAttempts: 5, RetryBackoffSeconds: 2, TimeoutSeconds: 5}}
+	})
+}
+
+// GetConfig returns the webhook configuration, loaded and validated with the configuration file.
+func GetConfig() WebhookConfig {
+	return appConfig.Registered[*Config]("webhook.toml").Webhook
+}
+
+// Validate checks that the settings are usable, reporting every invalid one.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Webhook.Secret == "" && len(c.Webhook.AllowedHosts) > 0 {
+		errs = append(errs, errors.New("webhook.secret: must be set when allowed_hosts enables callbacks"))
+	}
+	if c.Webhook.MaxAttempts < 1 {
+		errs = append(errs, errors.New("webhook.max_attempts: must be at least 1"))
+	}
+	if c.Webhook.RetryBackoffSeconds < 0 {
+		errs = append(errs, errors.New("webhook.retry_backoff_seconds: must not be negative"))
+	}
+	if c.Webhook.TimeoutSeconds < 1 {
+		errs = append(errs, errors.New("webhook.timeout_seconds: must be at least 1"))
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateCallbackURL checks that a callback URL uses HTTP(S) and points to an allowed host.
+// Without allowed hosts callbacks are disabled and every URL is rejected.
+func ValidateCallbackURL(rawURL string, allowedHosts []string) error {
+	if len(allowedHosts) == 0 {
+		return errors.New("callbacks are disabled on this server")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback URL: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid callback URL: scheme must be http or https")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("callback host %q is not allowed", host)
+}
+
+// Sign returns the signature sent in SignatureHeader: "sha256=" followed by the hex encoded HMAC-SHA256
+// of the timestamp sent in TimestampHeader, a dot and body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,39 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCallbackURL(t *testing.T) {
+	allowedHosts := []string{"grader.example.com", "*.school.edu"}
+
+	assert.NoError(t, ValidateCallbackURL("https://grader.example.com/hook", allowedHosts), "Allowed host should be accepted")
+	assert.NoError(t, ValidateCallbackURL("http://cs.school.edu:8080/hook", allowedHosts), "Subdomain of a wildcard host should be accepted")
+	assert.Error(t, ValidateCallbackURL("https://evil.example.com/hook", allowedHosts), "Other hosts should be rejected")
+	assert.Error(t, ValidateCallbackURL("ftp://grader.example.com/hook", allowedHosts), "Non-HTTP schemes should be rejected")
+	assert.Error(t, ValidateCallbackURL("https://grader.example.com/hook", nil), "Callbacks should be rejected when no host is allowed")
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"status":"completed"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	assert.Equal(t, expected, Sign("secret", "1700000000", body), "Signature should be the HMAC-SHA256 of the timestamp and the body")
+	assert.NotEqual(t, expected, Sign("secret", "1700000300", body), "Signature should change with the timestamp")
+}
+
+func TestValidate(t *testing.T) {
+	disabled := Config{Webhook: WebhookConfig{MaxAttempts: 5, TimeoutSeconds: 5}}
+	assert.NoError(t, disabled.Validate(), "Without allowed hosts no secret is needed")
+
+	config := Config{Webhook: WebhookConfig{AllowedHosts: []string{"grader.example.com"}, MaxAttempts: 5}}
+	assert.EqualError(t, config.Validate(), "webhook.secret: must be set when allowed_hosts enables callbacks\nwebhook.timeout_seconds: must be at least 1")
+}
@@ -2,8 +2,10 @@ package tests
 
 import (
 	"CodeXecutor/models"
+	"CodeXecutor/pkg/config"
 	"CodeXecutor/pkg/redis"
 	"CodeXecutor/utils"
+	"context"
 	"testing"
 	"time"
 
@@ -12,8 +14,10 @@ import (
 
 // TestFullFlow tests the full end-to-end flow of enqueueing, dequeuing, and caching.
 func TestFullFlow(t *testing.T) {
-	client := redis.ConnectRedis()
-	defer client.Close()
+	ctx := context.Background()
+	store, err := redis.NewStore(ctx, config.Default().Redis)
+	assert.NoError(t, err, "Error connecting to Redis")
+	defer store.Close()
 
 	// Create a sample job for testing
 	job := models.Job{
@@ -24,21 +28,21 @@ func TestFullFlow(t *testing.T) {
 	}
 
 	// Enqueue the job
-	err := redis.EnqueueItem("test-queue", job)
+	err = store.EnqueueItem(ctx, "test-queue", job)
 	assert.NoError(t, err, "Error enqueuing item")
 
 	// Dequeue the job
-	dequeuedJob, err := redis.DequeueItem("test-queue")
+	dequeuedJob, err := store.DequeueItem(ctx, "test-queue")
 	assert.NoError(t, err, "Error dequeuing item")
 	assert.Equal(t, job, dequeuedJob, "Enqueued and dequeued jobs should be equal")
 
 	// Set and get data in cache
 	key := utils.GenerateUniqueID()
 	data := map[string]interface{}{"status": "success"}
-	err = redis.SetCache(key, data, time.Minute)
+	err = store.SetCache(ctx, key, data, time.Minute)
 	assert.NoError(t, err, "Error setting data in cache")
 
-	result, err := redis.GetCache(key)
+	result, err := store.GetCache(ctx, key)
 	assert.NoError(t, err, "Error getting data from cache")
 	assert.NotEmpty(t, result, "Cached data should not be empty")
 